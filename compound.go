@@ -0,0 +1,264 @@
+package xl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// A CompoundQuery combines the results of several SELECT queries with UNION,
+// UNION ALL, INTERSECT or EXCEPT. ORDER BY and LIMIT apply to the combined
+// result.
+//
+//	q := xl.Union(xl.Select("name").From("employee"), xl.Select("name").From("department"))
+//	q.OrderBy("name")
+type CompoundQuery struct {
+	parts   []compoundPart
+	orderBy []Order
+	limit   *limitOffset
+}
+
+type compoundPart struct {
+	op    string
+	query *SelectQuery
+}
+
+func newCompound(op string, queries []*SelectQuery) *CompoundQuery {
+	q := &CompoundQuery{}
+	for _, sq := range queries {
+		q.add(op, sq)
+	}
+	return q
+}
+
+// Union combines queries with UNION.
+func Union(queries ...*SelectQuery) *CompoundQuery {
+	return newCompound("UNION", queries)
+}
+
+// UnionAll combines queries with UNION ALL.
+func UnionAll(queries ...*SelectQuery) *CompoundQuery {
+	return newCompound("UNION ALL", queries)
+}
+
+// Intersect combines queries with INTERSECT.
+func Intersect(queries ...*SelectQuery) *CompoundQuery {
+	return newCompound("INTERSECT", queries)
+}
+
+// Except combines queries with EXCEPT.
+func Except(queries ...*SelectQuery) *CompoundQuery {
+	return newCompound("EXCEPT", queries)
+}
+
+func (q *CompoundQuery) add(op string, sq *SelectQuery) *CompoundQuery {
+	if len(q.parts) == 0 {
+		op = ""
+	}
+	q.parts = append(q.parts, compoundPart{op, sq})
+	return q
+}
+
+// Union appends sq with UNION.
+func (q *CompoundQuery) Union(sq *SelectQuery) *CompoundQuery {
+	return q.add("UNION", sq)
+}
+
+// UnionAll appends sq with UNION ALL.
+func (q *CompoundQuery) UnionAll(sq *SelectQuery) *CompoundQuery {
+	return q.add("UNION ALL", sq)
+}
+
+// Intersect appends sq with INTERSECT.
+func (q *CompoundQuery) Intersect(sq *SelectQuery) *CompoundQuery {
+	return q.add("INTERSECT", sq)
+}
+
+// Except appends sq with EXCEPT.
+func (q *CompoundQuery) Except(sq *SelectQuery) *CompoundQuery {
+	return q.add("EXCEPT", sq)
+}
+
+// OrderBy appends expr to the ORDER BY clause of the combined result.
+func (q *CompoundQuery) OrderBy(expr string, params ...interface{}) {
+	q.orderBy = append(q.orderBy, Asc(expr, params...))
+}
+
+// OrderByDesc appends expr in descending order to the ORDER BY clause of the
+// combined result.
+func (q *CompoundQuery) OrderByDesc(expr string, params ...interface{}) {
+	q.orderBy = append(q.orderBy, Desc(expr, params...))
+}
+
+// AddOrder appends orders to the ORDER BY clause of the combined result.
+func (q *CompoundQuery) AddOrder(orders ...Order) {
+	q.orderBy = append(q.orderBy, orders...)
+}
+
+// LimitOffset sets the LIMIT and OFFSET of the combined result.
+func (q *CompoundQuery) LimitOffset(limit, offset int64) *CompoundQuery {
+	q.limit = &limitOffset{limit, offset}
+	return q
+}
+
+func (q *CompoundQuery) Statement(d Dialect) (*Statement, error) {
+	var s bytes.Buffer
+	params := make([]interface{}, 0)
+
+	if err := q.writeCompound(&s, &params, d); err != nil {
+		return nil, err
+	}
+
+	query := s.String()
+
+	if d.BindType == sqlx.DOLLAR {
+		query = sqlx.Rebind(d.BindType, query)
+	}
+
+	return New(query, params...), nil
+}
+
+func (q *CompoundQuery) writeCompound(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	if len(q.parts) == 0 {
+		return errors.New("no queries")
+	}
+
+	for i, part := range q.parts {
+		sq := part.query
+
+		if len(sq.exprs) == 0 && len(sq.cols) == 0 {
+			return errors.New("no columns")
+		}
+
+		if len(sq.orderBy) > 0 || sq.limit != nil {
+			return errors.New("ORDER BY and LIMIT not supported in compound query branch")
+		}
+
+		if sq.lock.strength != "" {
+			return errors.New("locking not supported in compound query branch")
+		}
+
+		if len(sq.with.ctes) > 0 {
+			return errors.New("WITH not supported in compound query branch")
+		}
+
+		if i > 0 {
+			s.WriteString(" " + part.op + " ")
+		}
+
+		if err := sq.writeSelect(s, params, d); err != nil {
+			return err
+		}
+	}
+
+	if err := writeOrderBy(s, params, q.orderBy, d); err != nil {
+		return err
+	}
+
+	if q.limit != nil {
+		s.WriteString(fmt.Sprintf(" LIMIT %d OFFSET %d", q.limit.limit, q.limit.offset))
+	}
+
+	return nil
+}
+
+func (q *CompoundQuery) Queryx(queryer Queryer) (*sqlx.Rows, error) {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	return st.Queryx(queryer)
+}
+
+func (q *CompoundQuery) QueryxContext(ctx context.Context, queryer Queryer) (*sqlx.Rows, error) {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	return st.QueryxContext(ctx, queryer)
+}
+
+func (q *CompoundQuery) First(queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.First(queryer, dest)
+}
+
+func (q *CompoundQuery) FirstContext(ctx context.Context, queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.FirstContext(ctx, queryer, dest)
+}
+
+func (q *CompoundQuery) All(queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.All(queryer, dest)
+}
+
+func (q *CompoundQuery) AllContext(ctx context.Context, queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.AllContext(ctx, queryer, dest)
+}
+
+// Total runs the combined query without ORDER BY and LIMIT/OFFSET and returns
+// the number of rows.
+func (q *CompoundQuery) Total(queryer Queryer) (int, error) {
+	st, err := q.totalStatement(queryer.Dialect())
+
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = st.QueryRowx(queryer).Scan(&count)
+
+	return count, err
+}
+
+// TotalContext is like Total but passes ctx to the database.
+func (q *CompoundQuery) TotalContext(ctx context.Context, queryer Queryer) (int, error) {
+	st, err := q.totalStatement(queryer.Dialect())
+
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = st.QueryRowxContext(ctx, queryer).Scan(&count)
+
+	return count, err
+}
+
+func (q *CompoundQuery) totalStatement(d Dialect) (*Statement, error) {
+	tq := &CompoundQuery{parts: q.parts}
+
+	var s bytes.Buffer
+	params := make([]interface{}, 0)
+
+	s.WriteString("SELECT COUNT(*) FROM (")
+	if err := tq.writeCompound(&s, &params, d); err != nil {
+		return nil, err
+	}
+	s.WriteString(") t")
+
+	query := s.String()
+
+	if d.BindType == sqlx.DOLLAR {
+		query = sqlx.Rebind(d.BindType, query)
+	}
+
+	return New(query, params...), nil
+}
@@ -0,0 +1,83 @@
+package xl_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func TestCompound(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	{
+		var names []string
+
+		q1 := xl.Select("name").From("employee")
+		q1.Where("salary>?", 10000)
+		q2 := xl.Select("name").From("department")
+		q2.Where("city=?", "Hong Kong")
+
+		q := xl.Union(q1, q2)
+		q.OrderBy("name")
+		q.LimitOffset(2, 1)
+
+		requireSQL(t, "SELECT name FROM employee WHERE salary>? UNION SELECT name FROM department WHERE city=? ORDER BY name LIMIT 2 OFFSET 1", q)
+
+		st, err := q.Statement(xl.Dialect{BindType: sqlx.DOLLAR})
+		require.Nil(t, err)
+		require.Equal(t, "SELECT name FROM employee WHERE salary>$1 UNION SELECT name FROM department WHERE city=$2 ORDER BY name LIMIT 2 OFFSET 1", st.SQL)
+		require.Equal(t, []interface{}{10000, "Hong Kong"}, st.Params)
+
+		require.Nil(t, q.All(db, &names))
+		require.Equal(t, []string{"Eliza Yxa", "R&D"}, names)
+
+		total, err := q.Total(db)
+		require.Nil(t, err)
+		require.Equal(t, 3, total)
+	}
+
+	{
+		var ids []int64
+
+		q := xl.UnionAll(xl.Select("department_id").From("employee"), xl.Select("id").From("department"))
+		q.Except(xl.Select("?", 1))
+		requireSQL(t, "SELECT department_id FROM employee UNION ALL SELECT id FROM department EXCEPT SELECT ?", q)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{2}, ids)
+	}
+
+	{
+		var id int64
+
+		q := xl.Intersect(xl.Select("department_id").From("employee"), xl.Select("id").From("department"))
+		q.OrderBy("1 DESC")
+		require.Nil(t, q.First(db, &id))
+		require.Equal(t, int64(2), id)
+
+		total, err := q.Total(db)
+		require.Nil(t, err)
+		require.Equal(t, 2, total)
+	}
+
+	{
+		_, err := xl.Union().Statement(xl.Dialect{})
+		require.NotNil(t, err)
+
+		sq := xl.Select("id").From("employee")
+		sq.LimitOffset(1, 0)
+		_, err = xl.Union(sq, xl.Select("id").From("department")).Statement(xl.Dialect{})
+		require.NotNil(t, err)
+
+		q := xl.Union(xl.Select("id").From("employee"), xl.Select("id").From("department"))
+		q.OrderBy("id + ?", []int{})
+		_, err = q.Statement(xl.Dialect{})
+		require.NotNil(t, err)
+	}
+}
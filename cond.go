@@ -0,0 +1,396 @@
+package xl
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// A Condition is a boolean SQL expression that can be passed to Where.
+// Conditions are built with Expr, And, Or and Not and can be nested
+// arbitrarily.
+type Condition interface {
+	writeCondition(s *bytes.Buffer, params *[]interface{}, d Dialect) error
+}
+
+// Expr builds a Condition from an SQL expression and its parameters. Like
+// Where, Expr doesn't surround the expression with parentheses, but And and Or
+// do when combining it with other conditions if it contains AND or OR.
+func Expr(expr string, params ...interface{}) Condition {
+	return exprParams{expr, params}
+}
+
+// And builds a Condition that is true if all conditions are true. An empty
+// And is always true.
+func And(conds ...Condition) Condition {
+	return compoundCondition{"AND", "1=1", conds}
+}
+
+// Or builds a Condition that is true if any condition is true. An empty Or is
+// always false.
+func Or(conds ...Condition) Condition {
+	return compoundCondition{"OR", "1=0", conds}
+}
+
+// Not builds a Condition that negates cond.
+func Not(cond Condition) Condition {
+	return notCondition{cond}
+}
+
+func (e exprParams) writeCondition(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	return writeExpr(s, params, e.expr, e.params, d)
+}
+
+type compoundCondition struct {
+	op    string
+	empty string
+	conds []Condition
+}
+
+func (c compoundCondition) writeCondition(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	if len(c.conds) == 0 {
+		s.WriteString(c.empty)
+		return nil
+	}
+
+	if len(c.conds) == 1 {
+		return c.conds[0].writeCondition(s, params, d)
+	}
+
+	s.WriteString("(")
+	for i := range c.conds {
+		if i > 0 {
+			s.WriteString(" " + c.op + " ")
+		}
+		if err := writeOperand(s, params, c.conds[i], d); err != nil {
+			return err
+		}
+	}
+	s.WriteString(")")
+
+	return nil
+}
+
+// writeOperand writes cond as an operand of AND or OR, surrounded by
+// parentheses unless it's already delimited. An Expr is only surrounded by
+// parentheses if it contains AND or OR itself.
+func writeOperand(s *bytes.Buffer, params *[]interface{}, cond Condition, d Dialect) error {
+	if delimited(cond) {
+		return cond.writeCondition(s, params, d)
+	}
+
+	s.WriteString("(")
+	if err := cond.writeCondition(s, params, d); err != nil {
+		return err
+	}
+	s.WriteString(")")
+
+	return nil
+}
+
+// delimited returns true if cond is written in a form that binds tighter than
+// AND, e.g. a parenthesized list or a NOT.
+func delimited(cond Condition) bool {
+	switch c := cond.(type) {
+	case compoundCondition:
+		if len(c.conds) == 1 {
+			return delimited(c.conds[0])
+		}
+		return true
+	case notCondition, inCondition:
+		return true
+	case exprParams:
+		return !hasTopLevelLogic(c.expr)
+	default:
+		return false
+	}
+}
+
+// hasTopLevelLogic returns true if expr contains AND or OR outside of
+// parentheses and quotes.
+func hasTopLevelLogic(expr string) bool {
+	depth := 0
+
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '\'', '"':
+			if j := strings.IndexByte(expr[i+1:], c); j >= 0 {
+				i += j + 1
+			}
+		default:
+			if depth != 0 || (i > 0 && isIdentByte(expr[i-1])) {
+				continue
+			}
+			for _, op := range []string{"AND", "OR"} {
+				end := i + len(op)
+				if end <= len(expr) && strings.EqualFold(expr[i:end], op) &&
+					(end == len(expr) || !isIdentByte(expr[end])) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+type notCondition struct {
+	cond Condition
+}
+
+func (c notCondition) writeCondition(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	if cc, ok := c.cond.(compoundCondition); ok && len(cc.conds) > 1 {
+		// Already surrounded by parentheses.
+		s.WriteString("NOT ")
+		return cc.writeCondition(s, params, d)
+	}
+
+	s.WriteString("NOT (")
+	if err := c.cond.writeCondition(s, params, d); err != nil {
+		return err
+	}
+	s.WriteString(")")
+
+	return nil
+}
+
+// errCondition is a Condition that fails to build. It records misuse of Where
+// until the statement is built.
+type errCondition struct {
+	err error
+}
+
+func (c errCondition) writeCondition(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	return c.err
+}
+
+// toCondition converts the arguments passed to Where to a Condition. cond is
+// either an SQL expression string or a Condition.
+func toCondition(cond interface{}, params []interface{}) Condition {
+	switch c := cond.(type) {
+	case string:
+		return exprParams{c, params}
+	case Condition:
+		if len(params) > 0 {
+			return errCondition{fmt.Errorf("params not allowed with a Condition")}
+		}
+		return c
+	}
+
+	if v := reflect.ValueOf(cond); v.Kind() == reflect.String {
+		return exprParams{v.String(), params}
+	}
+
+	return errCondition{fmt.Errorf("unsupported condition type %T", cond)}
+}
+
+func copyConditions(a []Condition) []Condition {
+	if a == nil {
+		return nil
+	}
+
+	b := make([]Condition, len(a))
+	copy(b, a)
+
+	return b
+}
+
+// In builds a Condition that is true if col equals any of values. values is
+// typically a slice, in which case it's expanded to one placeholder per
+// element. An empty slice yields a condition that is always false.
+//
+//	q.Where(xl.In("id", []int64{1, 2, 3}))
+func In(col string, values interface{}) Condition {
+	return inCondition{col, values, false}
+}
+
+// NotIn is the negation of In. An empty slice yields a condition that is
+// always true.
+func NotIn(col string, values interface{}) Condition {
+	return inCondition{col, values, true}
+}
+
+// Exists builds a Condition that is true if sq returns any rows.
+//
+//	sq := xl.Select("1").FromAs("employee", "e")
+//	sq.Where("e.department_id=d.id")
+//	q.Where(xl.Exists(sq))
+func Exists(sq *SelectQuery) Condition {
+	return exprParams{"EXISTS ?", []interface{}{sq}}
+}
+
+// NotExists builds a Condition that is true if sq returns no rows.
+func NotExists(sq *SelectQuery) Condition {
+	return exprParams{"NOT EXISTS ?", []interface{}{sq}}
+}
+
+// InSelect builds a Condition that is true if col equals any value returned by
+// sq.
+func InSelect(col string, sq *SelectQuery) Condition {
+	return exprParams{col + " IN ?", []interface{}{sq}}
+}
+
+// NotInSelect is the negation of InSelect.
+func NotInSelect(col string, sq *SelectQuery) Condition {
+	return exprParams{col + " NOT IN ?", []interface{}{sq}}
+}
+
+type inCondition struct {
+	col    string
+	values interface{}
+	not    bool
+}
+
+func (c inCondition) writeCondition(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	if v, ok := sliceParam(c.values); ok && v.Len() == 0 {
+		if c.not {
+			s.WriteString("1=1")
+		} else {
+			s.WriteString("1=0")
+		}
+		return nil
+	}
+
+	if c.not {
+		s.WriteString(c.col + " NOT IN ")
+	} else {
+		s.WriteString(c.col + " IN ")
+	}
+
+	return writeParam(s, params, c.values, d)
+}
+
+// emptyInExpr matches expressions that In and NotIn can stand in for, e.g.
+// "id IN ?" and "e.id NOT IN ?". The left side must be a bare column since
+// the whole expression is replaced.
+var emptyInExpr = regexp.MustCompile(`(?is)^\s*([\w.]+)\s+(NOT\s+)?IN\s*\?\s*$`)
+
+// writeExpr writes an SQL expression and its parameters. Slice parameters are
+// expanded to a parenthesized list of placeholders, e.g. "id IN ?" with
+// []int{1, 2} becomes "id IN (?, ?)". An expression like "id IN ?" or
+// "id NOT IN ?" with an empty slice is written like In or NotIn would write it.
+// Other uses of an empty slice are an error since SQL has no empty lists.
+// Statementer parameters, e.g. a *SelectQuery, are inlined as parenthesized
+// subqueries.
+func writeExpr(s *bytes.Buffer, params *[]interface{}, expr string, args []interface{}, d Dialect) error {
+	if len(args) == 1 {
+		if v, ok := sliceParam(args[0]); ok && v.Len() == 0 {
+			if m := emptyInExpr.FindStringSubmatch(expr); m != nil {
+				return inCondition{m[1], args[0], m[2] != ""}.writeCondition(s, params, d)
+			}
+		}
+	}
+
+	expand := false
+
+	for i := range args {
+		if _, ok := sliceParam(args[i]); ok {
+			expand = true
+			break
+		}
+		if _, ok := args[i].(Statementer); ok {
+			expand = true
+			break
+		}
+	}
+
+	if !expand {
+		s.WriteString(expr)
+		*params = append(*params, args...)
+		return nil
+	}
+
+	n := 0
+
+	for i := 0; i < len(expr); i++ {
+		if expr[i] == '?' && n < len(args) {
+			if err := writeParam(s, params, args[n], d); err != nil {
+				return err
+			}
+			n++
+		} else {
+			s.WriteByte(expr[i])
+		}
+	}
+
+	*params = append(*params, args[n:]...)
+
+	return nil
+}
+
+func writeParam(s *bytes.Buffer, params *[]interface{}, param interface{}, d Dialect) error {
+	if sq, ok := param.(Statementer); ok {
+		return writeSubquery(s, params, sq, d)
+	}
+
+	v, ok := sliceParam(param)
+
+	if !ok {
+		s.WriteString("?")
+		*params = append(*params, param)
+		return nil
+	}
+
+	if v.Len() == 0 {
+		return fmt.Errorf("empty slice parameter, use In or NotIn")
+	}
+
+	s.WriteString("(")
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			s.WriteString(", ")
+		}
+		s.WriteString("?")
+		*params = append(*params, v.Index(i).Interface())
+	}
+	s.WriteString(")")
+
+	return nil
+}
+
+// writeSubquery writes a parenthesized subquery. The subquery is compiled with
+// ? placeholders so that the whole statement can be rebound at once.
+func writeSubquery(s *bytes.Buffer, params *[]interface{}, sq Statementer, d Dialect) error {
+	st, err := sq.Statement(d.unbound())
+
+	if err != nil {
+		return err
+	}
+
+	s.WriteString("(" + st.SQL + ")")
+	*params = append(*params, st.Params...)
+
+	return nil
+}
+
+// sliceParam returns param as a reflect.Value if it's a slice that should be
+// expanded. Byte slices and driver.Valuer implementations are passed to the
+// driver as is.
+func sliceParam(param interface{}) (reflect.Value, bool) {
+	if param == nil {
+		return reflect.Value{}, false
+	}
+
+	if _, ok := param.(driver.Valuer); ok {
+		return reflect.Value{}, false
+	}
+
+	v := reflect.ValueOf(param)
+
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return reflect.Value{}, false
+	}
+
+	return v, true
+}
@@ -0,0 +1,346 @@
+package xl_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func TestCondition(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		q.Where(xl.Or(xl.Expr("name=?", "Bob Älv"), xl.Expr("salary>?", 10000)))
+		q.Where("department_id=?", 2)
+		q.OrderBy("id")
+		requireSQL(t, "SELECT id FROM employee WHERE (name=? OR salary>?) AND department_id=? ORDER BY id", q)
+		st, err := q.Statement(db.Dialect())
+		require.Nil(t, err)
+		require.Equal(t, []interface{}{"Bob Älv", 10000, 2}, st.Params)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{2, 5}, ids)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		q.Where(xl.Not(xl.Or(
+			xl.And(xl.Expr("department_id=?", 1), xl.Expr("salary<?", 11000)),
+			xl.Expr("id=?", 4),
+		)))
+		q.OrderBy("id")
+		requireSQL(t, "SELECT id FROM employee WHERE NOT ((department_id=? AND salary<?) OR id=?) ORDER BY id", q)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{1, 2, 5}, ids)
+	}
+
+	{
+		q := xl.Select("id").From("employee")
+		q.Where(xl.Not(xl.Expr("id=?", 1)))
+		q.Where(xl.And(xl.Expr("salary>?", 1)))
+		requireSQL(t, "SELECT id FROM employee WHERE NOT (id=?) AND salary>?", q)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		q.Where(xl.And(
+			xl.Expr("name=? OR salary>?", "Bob Älv", 10000),
+			xl.Or(xl.Expr("department_id=?", 2), xl.In("id", []int64{1})),
+			xl.Expr("id<>?", 5),
+		))
+		q.OrderBy("id")
+		requireSQL(t, "SELECT id FROM employee WHERE ((name=? OR salary>?) AND (department_id=? OR id IN (?)) AND id<>?) ORDER BY id", q)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{1, 2}, ids)
+	}
+
+	{
+		q := xl.Select("id").From("employee")
+		q.Where(xl.Or(xl.Expr("salary BETWEEN ? AND ?", 1, 2), xl.Expr("name='Android' OR name=?", "Orvar"), xl.Expr("(a OR b)")))
+		requireSQL(t, "SELECT id FROM employee WHERE ((salary BETWEEN ? AND ?) OR (name='Android' OR name=?) OR (a OR b))", q)
+
+		q = xl.Select("id").From("employee")
+		q.Where(xl.Or(xl.Expr("name='x AND y'"), xl.Expr("brand=?", 1)))
+		requireSQL(t, "SELECT id FROM employee WHERE (name='x AND y' OR brand=?)", q)
+	}
+
+	{
+		q := xl.Select("id").From("employee")
+		q.Where(xl.And())
+		q.Where(xl.Or())
+		requireSQL(t, "SELECT id FROM employee WHERE 1=1 AND 1=0", q)
+	}
+
+	{
+		q := xl.Update("employee")
+		q.Set("salary", 1)
+		q.Where(xl.Or(xl.Expr("id=?", 1), xl.Expr("id=?", 2)))
+		requireSQL(t, "UPDATE employee SET salary=? WHERE (id=? OR id=?)", q)
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(2), count)
+	}
+
+	{
+		q := xl.Delete("employee")
+		q.Where(xl.Or(xl.Expr("id=?", 1), xl.Expr("id=?", 2)))
+		requireSQL(t, "DELETE FROM employee WHERE (id=? OR id=?)", q)
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(2), count)
+	}
+
+	{
+		type expr string
+		q := xl.Select("id").From("employee")
+		q.Where(expr("id=?"), 1)
+		requireSQL(t, "SELECT id FROM employee WHERE id=?", q)
+	}
+
+	{
+		q := xl.Delete("employee")
+		q.Where(42)
+		_, err := q.Statement(xl.Dialect{})
+		require.NotNil(t, err)
+
+		q = xl.Delete("employee")
+		q.Where(xl.Expr("id=1"), 1)
+		_, err = q.ExecCount(db)
+		require.NotNil(t, err)
+	}
+}
+
+func ExampleOr() {
+	q := xl.Select("salary").From("employee")
+	q.Where("name=?", "Alice Örn")
+	q.Where(xl.Or(xl.Expr("city=?", "Hong Kong"), xl.Expr("city=?", "Stockholm")))
+	st, _ := q.Statement(xl.Dialect{})
+	fmt.Println(st.SQL)
+
+	// Output:
+	// SELECT salary FROM employee WHERE name=? AND (city=? OR city=?)
+}
+
+func TestIn(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		q.Where("department_id=?", 2)
+		q.Where("id IN ?", []int64{1, 2, 4})
+		q.OrderBy("id")
+		requireSQL(t, "SELECT id FROM employee WHERE department_id=? AND id IN (?, ?, ?) ORDER BY id", q)
+		st, err := q.Statement(xl.Dialect{BindType: sqlx.DOLLAR})
+		require.Nil(t, err)
+		require.Equal(t, "SELECT id FROM employee WHERE department_id=$1 AND id IN ($2, $3, $4) ORDER BY id", st.SQL)
+		require.Equal(t, []interface{}{2, int64(1), int64(2), int64(4)}, st.Params)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{2, 4}, ids)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		q.Where(xl.In("name", []string{"Bob Älv", "Eliza Yxa"}))
+		q.Where(xl.NotIn("id", []int{5}))
+		requireSQL(t, "SELECT id FROM employee WHERE name IN (?, ?) AND id NOT IN (?)", q)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{2}, ids)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		q.Where(xl.In("id", []int64{}))
+		requireSQL(t, "SELECT id FROM employee WHERE 1=0", q)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, 0, len(ids))
+	}
+
+	{
+		var count int
+		q := xl.Select("COUNT(*)").From("employee")
+		q.Where(xl.NotIn("id", []int64{}))
+		requireSQL(t, "SELECT COUNT(*) FROM employee WHERE 1=1", q)
+		require.Nil(t, q.First(db, &count))
+		require.Equal(t, 5, count)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		q.Where(xl.Or(xl.Expr("id IN ?", []int64(nil)), xl.Expr("id=?", 3)))
+		requireSQL(t, "SELECT id FROM employee WHERE (1=0 OR id=?)", q)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{3}, ids)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		q.Where("id not in ?", []int64{})
+		q.Where("salary>?", 10000)
+		q.OrderBy("id")
+		requireSQL(t, "SELECT id FROM employee WHERE 1=1 AND salary>? ORDER BY id", q)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{1, 5}, ids)
+	}
+
+	{
+		q := xl.Select("id").From("employee")
+		q.Where("id IN ? AND salary>?", []int64{}, 1)
+		_, err := q.Statement(xl.Dialect{})
+		require.NotNil(t, err)
+	}
+
+	{
+		q := xl.Select("id").From("employee")
+		q.Where("salary>10000 OR id IN ?", []int64{})
+		_, err := q.Statement(xl.Dialect{})
+		require.NotNil(t, err)
+
+		d := xl.Delete("employee")
+		d.Where("salary>10000 AND id NOT IN ?", []int64{})
+		_, err = d.ExecCount(db)
+		require.NotNil(t, err)
+
+		var count int
+		require.Nil(t, xl.Select("COUNT(*)").From("employee").First(db, &count))
+		require.Equal(t, 5, count)
+	}
+
+	{
+		q := xl.Select("id IN ? \"hit\"", []int{1, 2}).From("employee")
+		q.OrderBy("id IN ? DESC", []int{3})
+		st, err := q.Statement(xl.Dialect{})
+		require.Nil(t, err)
+		require.Equal(t, `SELECT id IN (?, ?) "hit" FROM employee ORDER BY id IN (?) DESC`, st.SQL)
+		require.Equal(t, []interface{}{1, 2, 3}, st.Params)
+	}
+
+	{
+		q := xl.Update("employee")
+		q.Set("name", []byte("blob"))
+		q.Where("id IN ?", []int64{1, 2})
+		requireSQL(t, "UPDATE employee SET name=? WHERE id IN (?, ?)", q)
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(2), count)
+	}
+
+	{
+		q := xl.Delete("employee")
+		q.Where(xl.In("id", []int64{1, 2}))
+		requireSQL(t, "DELETE FROM employee WHERE id IN (?, ?)", q)
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(2), count)
+	}
+}
+
+func TestSubqueryCondition(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	{
+		var names []string
+
+		sq := xl.Select("1").FromAs("employee", "e")
+		sq.Where("e.department_id=d.id")
+		sq.Where("e.salary>?", 11000)
+
+		q := xl.Select("d.name").FromAs("department", "d")
+		q.Where("d.id>?", 0)
+		q.Where(xl.Exists(sq))
+		q.Where("d.city<>?", "Paris")
+
+		requireSQL(t, "SELECT d.name FROM department d WHERE d.id>? AND EXISTS (SELECT 1 FROM employee e WHERE e.department_id=d.id AND e.salary>?) AND d.city<>?", q)
+
+		st, err := q.Statement(xl.Dialect{BindType: sqlx.DOLLAR})
+		require.Nil(t, err)
+		require.Equal(t, "SELECT d.name FROM department d WHERE d.id>$1 AND EXISTS (SELECT 1 FROM employee e WHERE e.department_id=d.id AND e.salary>$2) AND d.city<>$3", st.SQL)
+		require.Equal(t, []interface{}{0, 11000, "Paris"}, st.Params)
+
+		require.Nil(t, q.All(db, &names))
+		require.Equal(t, []string{"HR"}, names)
+
+		var others []string
+		q = xl.Select("d.name").FromAs("department", "d")
+		q.Where(xl.NotExists(sq))
+		require.Nil(t, q.All(db, &others))
+		require.Equal(t, []string{"R&D"}, others)
+	}
+
+	{
+		var ids []int64
+
+		sq := xl.Select("id").From("department")
+		sq.Where("city=?", "Stockholm")
+
+		q := xl.Select("id").From("employee")
+		q.Where(xl.InSelect("department_id", sq))
+		q.OrderBy("id")
+		requireSQL(t, "SELECT id FROM employee WHERE department_id IN (SELECT id FROM department WHERE city=?) ORDER BY id", q)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{1, 3}, ids)
+
+		var others []int64
+		q = xl.Select("id").From("employee")
+		q.Where(xl.NotInSelect("department_id", sq))
+		q.Where("salary>?", 10000)
+		require.Nil(t, q.All(db, &others))
+		require.Equal(t, []int64{5}, others)
+	}
+
+	{
+		var e []struct {
+			Name string `db:"name"`
+			Max  int64  `db:"max_salary"`
+		}
+
+		sq := xl.Select("MAX(salary)").FromAs("employee", "e")
+		sq.Where("e.department_id=d.id")
+		sq.Where("e.id<>?", 1)
+
+		q := xl.Select("d.name")
+		q.ColumnSubselectAs(sq, "max_salary")
+		q.FromAs("department", "d")
+		q.Where("d.id<?", 10)
+		q.OrderBy("d.id")
+
+		st, err := q.Statement(xl.Dialect{BindType: sqlx.DOLLAR})
+		require.Nil(t, err)
+		require.Equal(t, `SELECT d.name, (SELECT MAX(salary) FROM employee e WHERE e.department_id=d.id AND e.id<>$1) "max_salary" FROM department d WHERE d.id<$2 ORDER BY d.id`, st.SQL)
+
+		require.Nil(t, q.All(db, &e))
+		require.Equal(t, 2, len(e))
+		require.Equal(t, int64(10000), e[0].Max)
+		require.Equal(t, int64(11000), e[1].Max)
+	}
+
+	{
+		q := xl.Select("id").From("employee")
+		q.Where(xl.Exists(xl.NewSelect()))
+		_, err := q.Statement(xl.Dialect{})
+		require.NotNil(t, err)
+	}
+}
@@ -2,7 +2,10 @@ package xl
 
 import (
 	"context"
+	"database/sql"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 type Context interface {
@@ -15,14 +18,61 @@ type Context interface {
 	Commit() error
 }
 
+// A TXContext is a context that carries a database and, after Begin, a
+// transaction. It implements Execer and Queryer so it can be passed directly
+// to a builder or Statement, in which case statements run in the transaction
+// and are canceled along with the context.
+//
+//	ctx, err := ctx.Begin()
+//	q := xl.Update("employee")
+//	q.Set("salary", 13000)
+//	q.Where("id=?", id)
+//	err = q.ExecOne(ctx)
 type TXContext struct {
 	base context.Context
 	tx   *Tx
 }
 
+// txKey is the context key of the current Tx.
+type txKey struct{}
+
+// WithDB returns a context that carries db. The returned context, and any
+// context derived from it, can be passed to FromContext to get it back.
 func WithDB(ctx context.Context, db *DB) TXContext {
-	tx := &Tx{db, nil, false, false}
-	return TXContext{ctx, tx}
+	tx := &Tx{db: db}
+	return TXContext{context.WithValue(ctx, txKey{}, tx), tx}
+}
+
+// FromContext returns the TXContext of the database and transaction carried
+// by ctx, which may be derived from a TXContext with e.g. context.WithValue or
+// context.WithTimeout. The returned TXContext uses ctx as its context. If the
+// transaction has already been committed or rolled back, the returned
+// TXContext carries its nearest outer transaction that is still open, or only
+// the database if there is none. ok is false if ctx doesn't derive from
+// WithDB.
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		ctx, ok := xl.FromContext(r.Context())
+//		...
+//	}
+func FromContext(ctx context.Context) (TXContext, bool) {
+	c, ok := ctx.(TXContext)
+
+	if !ok {
+		tx, ok := ctx.Value(txKey{}).(*Tx)
+		if !ok {
+			return TXContext{}, false
+		}
+		c = TXContext{ctx, tx}
+	}
+
+	if open := c.tx.open(); open != nil {
+		c.tx = open
+	} else {
+		c.tx = &Tx{db: c.tx.db}
+	}
+
+	return c, true
 }
 
 func (c TXContext) Deadline() (time.Time, bool) {
@@ -54,13 +104,27 @@ func (c TXContext) Tx() *Tx {
 	return c.tx
 }
 
+// Begin starts a transaction, or a nested transaction if c is already in a
+// transaction. The transaction is rolled back if c is canceled.
 func (c TXContext) Begin() (TXContext, error) {
-	tx, err := c.tx.Beginxl()
+	return c.BeginTx(nil)
+}
+
+// BeginTx is like Begin but with options. See Tx.BeginTxl for how options
+// apply to nested transactions. If opts has a timeout, the returned context
+// has a corresponding deadline.
+func (c TXContext) BeginTx(opts *TxOptions) (TXContext, error) {
+	tx, err := c.tx.begin(c, opts)
 
 	if err != nil {
 		return c, err
 	}
 
+	if tx.ctx != nil && tx.savepoint == "" {
+		c.base = tx.ctx
+	}
+
+	c.base = context.WithValue(c.base, txKey{}, tx)
 	c.tx = tx
 	return c, nil
 }
@@ -72,3 +136,39 @@ func (c TXContext) Rollback() error {
 func (c TXContext) Commit() error {
 	return c.tx.Commit()
 }
+
+func (c TXContext) Dialect() Dialect {
+	return c.tx.Dialect()
+}
+
+func (c TXContext) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.tx.ExecContext(c, query, args...)
+}
+
+func (c TXContext) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.tx.ExecContext(ctx, query, args...)
+}
+
+func (c TXContext) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.tx.QueryContext(c, query, args...)
+}
+
+func (c TXContext) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.tx.QueryContext(ctx, query, args...)
+}
+
+func (c TXContext) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	return c.tx.QueryxContext(c, query, args...)
+}
+
+func (c TXContext) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return c.tx.QueryxContext(ctx, query, args...)
+}
+
+func (c TXContext) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	return c.tx.QueryRowxContext(c, query, args...)
+}
+
+func (c TXContext) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	return c.tx.QueryRowxContext(ctx, query, args...)
+}
@@ -3,6 +3,7 @@ package xl_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/tomyl/xl"
@@ -113,3 +114,162 @@ func testNestedTx(t *testing.T, ctx xl.Context, rollback bool) error {
 
 	return ctx.Commit()
 }
+
+func TestContextExec(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		require.Equal(t, context.Canceled, q.AllContext(ctx, db, &ids))
+
+		u := xl.Update("employee")
+		u.Set("salary", 0)
+		u.Where("id=?", 1)
+		require.Equal(t, context.Canceled, u.ExecOneContext(ctx, db))
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		q.OrderBy("id")
+		require.Nil(t, q.AllContext(context.Background(), db, &ids))
+		require.Equal(t, []int64{1, 2, 3, 4, 5}, ids)
+
+		total, err := q.TotalContext(context.Background(), db)
+		require.Nil(t, err)
+		require.Equal(t, 5, total)
+	}
+
+	{
+		ctx, err := xl.WithDB(context.Background(), db).Begin()
+		require.Nil(t, err)
+
+		// A TXContext passes itself as context and runs in its transaction.
+		q := xl.Update("employee")
+		q.Set("salary", 20000)
+		q.Where("id=?", 1)
+		require.Nil(t, q.ExecOne(ctx))
+
+		var salary int
+		s := xl.Select("salary").From("employee")
+		s.Where("id=?", 1)
+		require.Nil(t, s.First(ctx, &salary))
+		require.Equal(t, 20000, salary)
+
+		require.Nil(t, ctx.Rollback())
+		require.Nil(t, s.FirstContext(context.Background(), db, &salary))
+		require.Equal(t, 12000, salary)
+	}
+}
+
+type testKey struct{}
+
+func TestFromContext(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	_, ok := xl.FromContext(context.Background())
+	require.False(t, ok)
+
+	txctx, err := xl.WithDB(context.Background(), db).Begin()
+	require.Nil(t, err)
+	defer txctx.Rollback()
+
+	ctx, cancel := context.WithTimeout(context.WithValue(txctx, testKey{}, "value"), time.Minute)
+	defer cancel()
+
+	c, ok := xl.FromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, db, c.DB())
+	require.Equal(t, txctx.Tx(), c.Tx())
+	require.Equal(t, "value", c.Value(testKey{}))
+	_, ok = c.Deadline()
+	require.True(t, ok)
+
+	// Joins the transaction of the derived context.
+	err = xl.RunInTx(ctx, nil, nil, func(ctx xl.TXContext) error {
+		q := xl.Update("employee")
+		q.Set("salary", 20000)
+		q.Where("id=?", 1)
+		return q.ExecOne(ctx)
+	})
+	require.Nil(t, err)
+	require.Nil(t, txctx.Rollback())
+
+	var salary int
+	require.Nil(t, xl.New("SELECT salary FROM employee WHERE id=1").First(db, &salary))
+	require.Equal(t, 12000, salary)
+
+	// The transaction is over, so only the database is recovered.
+	c, ok = xl.FromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, db, c.DB())
+	require.NotEqual(t, txctx.Tx(), c.Tx())
+
+	err = xl.RunInTx(ctx, nil, nil, func(ctx xl.TXContext) error {
+		q := xl.Update("employee")
+		q.Set("salary", 21000)
+		q.Where("id=?", 1)
+		return q.ExecOne(ctx)
+	})
+	require.Nil(t, err)
+	require.Nil(t, xl.New("SELECT salary FROM employee WHERE id=1").First(db, &salary))
+	require.Equal(t, 21000, salary)
+
+	// An explicit database wins over one that isn't in a transaction.
+	other, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(other, selectSchema))
+
+	err = xl.RunInTx(xl.WithDB(context.Background(), db), other, nil, func(ctx xl.TXContext) error {
+		require.Equal(t, other, ctx.DB())
+		q := xl.Update("employee")
+		q.Set("salary", 22000)
+		q.Where("id=?", 1)
+		return q.ExecOne(ctx)
+	})
+	require.Nil(t, err)
+	require.Nil(t, xl.New("SELECT salary FROM employee WHERE id=1").First(other, &salary))
+	require.Equal(t, 22000, salary)
+	require.Nil(t, xl.New("SELECT salary FROM employee WHERE id=1").First(db, &salary))
+	require.Equal(t, 21000, salary)
+
+	// A finished nested transaction falls back to its open outer transaction.
+	outer, err := xl.WithDB(context.Background(), db).Begin()
+	require.Nil(t, err)
+	defer outer.Rollback()
+
+	inner, err := outer.Begin()
+	require.Nil(t, err)
+	require.Nil(t, inner.Commit())
+
+	ctx = context.WithValue(inner, testKey{}, "inner")
+	c, ok = xl.FromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, outer.Tx(), c.Tx())
+
+	err = xl.RunInTx(ctx, db, nil, func(ctx xl.TXContext) error {
+		q := xl.Insert("department")
+		q.Set("id", 3)
+		q.Set("name", "Sales")
+		q.Set("city", "Paris")
+		return q.ExecErr(ctx)
+	})
+	require.Nil(t, err)
+	require.Nil(t, outer.Rollback())
+
+	var count int
+	require.Nil(t, xl.New("SELECT COUNT(*) FROM department WHERE id=3").First(db, &count))
+	require.Equal(t, 0, count)
+}
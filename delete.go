@@ -2,14 +2,21 @@ package xl
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type DeleteQuery struct {
-	table string
-	where []exprParams
+	with      withClause
+	table     string
+	sources   []tableSource
+	where     []Condition
+	returning string
+	allRows   bool
 }
 
 func Delete(table string) *DeleteQuery {
@@ -18,22 +25,118 @@ func Delete(table string) *DeleteQuery {
 	}
 }
 
-// Where adds a WHERE clause. All WHERE clauses will be joined with AND. Note
-// that Where doesn't surround the expression with parentheses. See SelectQuery
-// doc for example.
-func (q *DeleteQuery) Where(expr string, params ...interface{}) {
+// Where adds a WHERE clause. All WHERE clauses will be joined with AND. cond
+// is either an SQL expression or a Condition. Note that Where doesn't surround
+// an SQL expression string with parentheses. See SelectQuery doc for example.
+func (q *DeleteQuery) Where(cond interface{}, params ...interface{}) {
 	if q.where == nil {
-		q.where = make([]exprParams, 0)
+		q.where = make([]Condition, 0)
 	}
-	q.where = append(q.where, exprParams{expr, params})
+	q.where = append(q.where, toCondition(cond, params))
+}
+
+// Using adds a table whose rows are referenced by Where. See Join for how it's
+// rendered.
+func (q *DeleteQuery) Using(table string) *DeleteQuery {
+	q.sources = append(q.sources, tableSource{table: tableAlias{name: table}})
+	return q
+}
+
+// UsingAs adds a table with an alias whose rows are referenced by Where.
+func (q *DeleteQuery) UsingAs(table, alias string) *DeleteQuery {
+	q.sources = append(q.sources, tableSource{table: tableAlias{name: table, alias: alias}})
+	return q
+}
+
+// UsingSubselectAs adds a subquery whose rows are referenced by Where.
+func (q *DeleteQuery) UsingSubselectAs(sq *SelectQuery, alias string) *DeleteQuery {
+	q.sources = append(q.sources, tableSource{table: tableAlias{subquery: sq, alias: alias}})
+	return q
+}
+
+// Join adds a table along with its join condition. Only rows of the table
+// passed to Delete are deleted. For MySQL it's rendered as DELETE t FROM t
+// JOIN table ON cond and otherwise as DELETE FROM t USING table WHERE cond.
+// SQLite supports neither.
+//
+//	q := xl.Delete("employee e")
+//	q.Join("department d", "d.id=e.department_id")
+//	q.Where("d.name=?", "Sales")
+func (q *DeleteQuery) Join(table string, cond interface{}, params ...interface{}) *DeleteQuery {
+	q.sources = append(q.sources, tableSource{tableAlias{name: table}, toCondition(cond, params)})
+	return q
+}
+
+// JoinSubselectAs adds a subquery along with its join condition. See Join.
+func (q *DeleteQuery) JoinSubselectAs(sq *SelectQuery, alias string, cond interface{}, params ...interface{}) *DeleteQuery {
+	q.sources = append(q.sources, tableSource{tableAlias{subquery: sq, alias: alias}, toCondition(cond, params)})
+	return q
+}
+
+func (q *DeleteQuery) Returning(expr string) {
+	q.returning = expr
+}
+
+// AllRows allows the query to delete all rows. Without it, Statement returns
+// ErrNoWhere if there is no WHERE clause.
+func (q *DeleteQuery) AllRows() *DeleteQuery {
+	q.allRows = true
+	return q
+}
+
+// With adds a common table expression. See SelectQuery.With.
+func (q *DeleteQuery) With(name string, query Statementer) *DeleteQuery {
+	q.with.add(name, query, false)
+	return q
+}
+
+// WithRecursive adds a common table expression and makes the WITH clause
+// RECURSIVE.
+func (q *DeleteQuery) WithRecursive(name string, query Statementer) *DeleteQuery {
+	q.with.add(name, query, true)
+	return q
 }
 
 func (q *DeleteQuery) Statement(d Dialect) (*Statement, error) {
+	if !q.allRows && !hasFilter(q.where, q.sources) {
+		return nil, fmt.Errorf("%w in DELETE FROM %s", ErrNoWhere, q.table)
+	}
+
 	var s bytes.Buffer
 	params := make([]interface{}, 0)
 
-	s.WriteString("DELETE FROM " + q.table)
-	writeWhere(&s, &params, q.where, 0)
+	if err := q.with.write(&s, &params, d); err != nil {
+		return nil, err
+	}
+
+	count := 0
+
+	if len(q.sources) == 0 {
+		s.WriteString("DELETE FROM " + q.table)
+	} else if d.isMySQL() {
+		// The alias, if any, names the table to delete from.
+		fields := strings.Fields(q.table)
+		s.WriteString("DELETE " + fields[len(fields)-1] + " FROM " + q.table)
+		if err := writeJoinSources(&s, &params, q.sources, d); err != nil {
+			return nil, err
+		}
+	} else if d.isSQLite() {
+		return nil, fmt.Errorf("DELETE with joins not supported by %s", d.Driver)
+	} else {
+		s.WriteString("DELETE FROM " + q.table)
+		var err error
+		if count, err = writeListSources(&s, &params, " USING ", q.sources, d); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := writeWhere(&s, &params, q.where, d, count); err != nil {
+		return nil, err
+	}
+
+	if q.returning != "" {
+		s.WriteString(" RETURNING " + q.returning)
+	}
 
 	query := s.String()
 
@@ -49,7 +152,15 @@ func (q *DeleteQuery) Exec(e Execer) (sql.Result, error) {
 	if err != nil {
 		return nil, err
 	}
-	return st.Exec(e)
+	return st.Exec(limitRows(e))
+}
+
+func (q *DeleteQuery) ExecContext(ctx context.Context, e Execer) (sql.Result, error) {
+	st, err := q.Statement(e.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	return st.ExecContext(ctx, limitRows(e))
 }
 
 func (q *DeleteQuery) ExecErr(e Execer) error {
@@ -57,7 +168,16 @@ func (q *DeleteQuery) ExecErr(e Execer) error {
 	if err != nil {
 		return err
 	}
-	_, err = st.Exec(e)
+	_, err = st.Exec(limitRows(e))
+	return err
+}
+
+func (q *DeleteQuery) ExecErrContext(ctx context.Context, e Execer) error {
+	st, err := q.Statement(e.Dialect())
+	if err != nil {
+		return err
+	}
+	_, err = st.ExecContext(ctx, limitRows(e))
 	return err
 }
 
@@ -66,7 +186,15 @@ func (q *DeleteQuery) ExecCount(e Execer) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return st.ExecCount(e)
+	return st.ExecCount(limitRows(e))
+}
+
+func (q *DeleteQuery) ExecCountContext(ctx context.Context, e Execer) (int64, error) {
+	st, err := q.Statement(e.Dialect())
+	if err != nil {
+		return 0, err
+	}
+	return st.ExecCountContext(ctx, limitRows(e))
 }
 
 func (q *DeleteQuery) ExecOne(e Execer) error {
@@ -74,5 +202,45 @@ func (q *DeleteQuery) ExecOne(e Execer) error {
 	if err != nil {
 		return err
 	}
-	return st.ExecOne(e)
+	return st.ExecOne(limitRows(e))
+}
+
+func (q *DeleteQuery) ExecOneContext(ctx context.Context, e Execer) error {
+	st, err := q.Statement(e.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.ExecOneContext(ctx, limitRows(e))
+}
+
+func (q *DeleteQuery) First(queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.First(queryer, dest)
+}
+
+func (q *DeleteQuery) FirstContext(ctx context.Context, queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.FirstContext(ctx, queryer, dest)
+}
+
+func (q *DeleteQuery) All(queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.All(queryer, dest)
+}
+
+func (q *DeleteQuery) AllContext(ctx context.Context, queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.AllContext(ctx, queryer, dest)
 }
@@ -35,3 +35,77 @@ func TestDelete(t *testing.T) {
 		require.Nil(t, q.ExecOne(db))
 	}
 }
+
+func TestDeleteJoin(t *testing.T) {
+	{
+		q := xl.Delete("employee e")
+		q.Join("department d", "d.id=e.department_id")
+		q.Where("d.name=?", "Sales")
+		q.Returning("e.id")
+
+		requireSQL(t, "DELETE FROM employee e USING department d WHERE d.id=e.department_id AND d.name=? RETURNING e.id", q)
+
+		_, err := q.Statement(xl.Dialect{Driver: "sqlite3"})
+		require.NotNil(t, err)
+	}
+
+	{
+		sq := xl.Select("employee_id").From("review")
+		sq.Where("score<?", 2)
+
+		q := xl.Delete("employee e")
+		q.JoinSubselectAs(sq, "r", "r.employee_id=e.id")
+		q.Using("department d")
+		q.Where("d.id=e.department_id AND d.name=?", "Sales")
+
+		st, err := q.Statement(xl.Dialect{Driver: "mysql"})
+		require.Nil(t, err)
+		require.Equal(t, "DELETE e FROM employee e JOIN (SELECT employee_id FROM review WHERE score<?) r ON r.employee_id=e.id, department d WHERE d.id=e.department_id AND d.name=?", st.SQL)
+		require.Equal(t, []interface{}{2, "Sales"}, st.Params)
+
+		st, err = q.Statement(xl.Dialect{Driver: "postgres"})
+		require.Nil(t, err)
+		require.Equal(t, "DELETE FROM employee e USING (SELECT employee_id FROM review WHERE score<?) r, department d WHERE r.employee_id=e.id AND d.id=e.department_id AND d.name=?", st.SQL)
+	}
+
+	{
+		xl.SetLogger(testlogger.Simple(t))
+
+		db, err := xl.Open("sqlite3", ":memory:")
+		require.Nil(t, err)
+		require.Nil(t, xl.MultiExec(db, deleteSchema))
+		require.Nil(t, xl.MultiExec(db, `
+create table review (employee_id integer not null, score integer not null);
+insert into review (employee_id, score) values (1, 4);
+insert into review (employee_id, score) values (2, 1);
+`))
+
+		sq := xl.Select("employee_id").From("review")
+		sq.Where("score<?", 2)
+
+		var e []struct {
+			ID   int64  `db:"id"`
+			Name string `db:"name"`
+		}
+
+		q := xl.Delete("employee")
+		q.Where(xl.InSelect("id", sq))
+		q.Returning("id, name")
+		requireSQL(t, "DELETE FROM employee WHERE id IN (SELECT employee_id FROM review WHERE score<?) RETURNING id, name", q)
+		require.Nil(t, q.All(db, &e))
+		require.Equal(t, 1, len(e))
+		require.Equal(t, int64(2), e[0].ID)
+		require.Equal(t, "Bob Älv", e[0].Name)
+
+		var id int64
+		q = xl.Delete("employee")
+		q.Where("salary>?", 10000)
+		q.Returning("id")
+		require.Nil(t, q.First(db, &id))
+		require.Equal(t, int64(1), id)
+
+		var count int
+		require.Nil(t, db.Get(&count, "SELECT COUNT(*) FROM employee"))
+		require.Equal(t, 0, count)
+	}
+}
@@ -4,7 +4,7 @@ go 1.13
 
 require (
 	github.com/jmoiron/sqlx v1.2.0
-	github.com/mattn/go-sqlite3 v1.9.0
+	github.com/mattn/go-sqlite3 v1.14.15
 	github.com/stretchr/testify v1.4.0
 	google.golang.org/appengine v1.6.6 // indirect
 )
@@ -0,0 +1,196 @@
+package xl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoWhere is returned when building an UPDATE or DELETE statement without
+// a WHERE clause, or with one that is always true such as an empty And. Call
+// AllRows to affect all rows on purpose.
+var ErrNoWhere = errors.New("missing WHERE clause")
+
+// ErrTooManyRows is returned when an UPDATE or DELETE affects more rows than
+// allowed by DB.SetMaxAffected.
+var ErrTooManyRows = errors.New("too many rows affected")
+
+// SetMaxAffected limits the number of rows a single UPDATE or DELETE built
+// with this package may affect. A statement executed on the DB runs in its
+// own transaction which is rolled back if the limit is exceeded. A statement
+// executed in a transaction runs in its own savepoint, so only the statement
+// is rolled back and the transaction can continue. Zero means no limit.
+func (db *DB) SetMaxAffected(n int64) {
+	db.maxAffected = n
+}
+
+// limitExecer enforces DB.SetMaxAffected. ctx is used by Exec.
+type limitExecer struct {
+	Execer
+	tx  *Tx
+	ctx context.Context
+}
+
+// limitRows wraps e in a limitExecer if its DB has a limit.
+func limitRows(e Execer) Execer {
+	switch v := e.(type) {
+	case *DB:
+		if v.maxAffected > 0 {
+			return limitExecer{e, &Tx{db: v}, context.Background()}
+		}
+	case *Tx:
+		if v.db.maxAffected > 0 {
+			return limitExecer{e, v, context.Background()}
+		}
+	case TXContext:
+		if v.tx.db.maxAffected > 0 {
+			return limitExecer{e, v.tx, v}
+		}
+	}
+	return e
+}
+
+func (l limitExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return l.ExecContext(l.ctx, query, args...)
+}
+
+func (l limitExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	// Either a transaction of its own or a savepoint in the caller's
+	// transaction.
+	tx, err := l.tx.begin(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := result.RowsAffected()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if max := tx.db.maxAffected; count > max {
+		if err := tx.Rollback(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %d rows, limit is %d", ErrTooManyRows, count, max)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// hasFilter reports whether rows are filtered by where or a join condition.
+// Conditions that are always true, e.g. an empty And, don't count.
+func hasFilter(where []Condition, sources []tableSource) bool {
+	for _, cond := range where {
+		if !alwaysTrue(cond) {
+			return true
+		}
+	}
+	for _, src := range sources {
+		if src.on != nil && !alwaysTrue(src.on) {
+			return true
+		}
+	}
+	return false
+}
+
+// alwaysTrue reports whether cond is written as a condition that is true for
+// every row.
+func alwaysTrue(cond Condition) bool {
+	switch c := cond.(type) {
+	case compoundCondition:
+		if c.op == "AND" {
+			for _, sub := range c.conds {
+				if !alwaysTrue(sub) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, sub := range c.conds {
+			if alwaysTrue(sub) {
+				return true
+			}
+		}
+		return false
+	case notCondition:
+		return alwaysFalse(c.cond)
+	case inCondition:
+		return c.not && emptySlice(c.values)
+	case exprParams:
+		return constantExpr(c, true)
+	default:
+		return false
+	}
+}
+
+// alwaysFalse reports whether cond is written as a condition that is false
+// for every row.
+func alwaysFalse(cond Condition) bool {
+	switch c := cond.(type) {
+	case compoundCondition:
+		if c.op == "OR" {
+			for _, sub := range c.conds {
+				if !alwaysFalse(sub) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, sub := range c.conds {
+			if alwaysFalse(sub) {
+				return true
+			}
+		}
+		return false
+	case notCondition:
+		return alwaysTrue(c.cond)
+	case inCondition:
+		return !c.not && emptySlice(c.values)
+	case exprParams:
+		return constantExpr(c, false)
+	default:
+		return false
+	}
+}
+
+// constantExpr reports whether e is written as the constant value, either
+// literally or as an IN expression with an empty slice.
+func constantExpr(e exprParams, value bool) bool {
+	literal := "1=0"
+	if value {
+		literal = "1=1"
+	}
+
+	if strings.TrimSpace(e.expr) == literal {
+		return true
+	}
+
+	if len(e.params) == 1 && emptySlice(e.params[0]) {
+		if m := emptyInExpr.FindStringSubmatch(e.expr); m != nil {
+			return (m[2] != "") == value
+		}
+	}
+
+	return false
+}
+
+func emptySlice(param interface{}) bool {
+	v, ok := sliceParam(param)
+	return ok && v.Len() == 0
+}
@@ -0,0 +1,128 @@
+package xl_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func TestNoWhere(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, updateSchema))
+
+	{
+		q := xl.Update("employee")
+		q.Set("salary", 0)
+		_, err := q.ExecCount(db)
+		require.True(t, errors.Is(err, xl.ErrNoWhere))
+
+		q.AllRows()
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(2), count)
+	}
+
+	{
+		q := xl.Delete("employee")
+		_, err := q.ExecCount(db)
+		require.True(t, errors.Is(err, xl.ErrNoWhere))
+
+		count, err := q.AllRows().ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(2), count)
+	}
+
+	{
+		for _, cond := range []xl.Condition{
+			xl.And(),
+			xl.NotIn("id", []int64{}),
+			xl.Expr("id NOT IN ?", []int64{}),
+			xl.Or(xl.Expr("id=?", 1), xl.And()),
+			xl.Not(xl.Or()),
+		} {
+			q := xl.Update("employee")
+			q.Set("salary", 0)
+			q.Where(cond)
+			_, err := q.ExecCount(db)
+			require.True(t, errors.Is(err, xl.ErrNoWhere))
+
+			d := xl.Delete("employee")
+			d.Where(cond)
+			_, err = d.ExecCount(db)
+			require.True(t, errors.Is(err, xl.ErrNoWhere))
+		}
+
+		q := xl.Delete("employee")
+		q.Where(xl.And(xl.Expr("id=?", 1), xl.And()))
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(0), count)
+	}
+}
+
+func TestMaxAffected(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, updateSchema))
+	db.SetMaxAffected(1)
+
+	{
+		q := xl.Update("employee")
+		q.Set("salary", 0)
+		q.Where("salary>?", 0)
+		_, err := q.ExecCount(db)
+		require.True(t, errors.Is(err, xl.ErrTooManyRows))
+		require.Equal(t, 12000, getSalary(t, db, 1))
+		require.Equal(t, 9000, getSalary(t, db, 2))
+	}
+
+	{
+		q := xl.Update("employee")
+		q.Set("salary", 10000)
+		q.Where("id=?", 2)
+		require.Nil(t, q.ExecOne(db))
+		require.Equal(t, 10000, getSalary(t, db, 2))
+	}
+
+	{
+		tx, err := db.Beginxl()
+		require.Nil(t, err)
+
+		q := xl.Update("employee")
+		q.Set("salary", 11000)
+		q.Where("id=?", 2)
+		require.Nil(t, q.ExecOne(tx))
+
+		d := xl.Delete("employee")
+		d.AllRows()
+		_, err = d.ExecCount(tx)
+		require.True(t, errors.Is(err, xl.ErrTooManyRows))
+
+		// Only the DELETE was rolled back.
+		var count int
+		require.Nil(t, xl.New("SELECT COUNT(*) FROM employee").First(tx, &count))
+		require.Equal(t, 2, count)
+		require.Equal(t, 11000, getSalary(t, tx, 2))
+
+		ntx, err := tx.Beginxl()
+		require.Nil(t, err)
+		_, err = d.ExecCount(ntx)
+		require.True(t, errors.Is(err, xl.ErrTooManyRows))
+		q = xl.Update("employee")
+		q.Set("salary", 12000)
+		q.Where("id=?", 2)
+		require.Nil(t, q.ExecOne(ntx))
+		require.Nil(t, ntx.Commit())
+
+		require.Nil(t, tx.Commit())
+		require.Equal(t, 12000, getSalary(t, db, 2))
+	}
+}
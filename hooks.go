@@ -0,0 +1,118 @@
+package xl
+
+import "fmt"
+
+// txHooks holds the hooks of a transaction and its nested transactions.
+type txHooks struct {
+	commit   []func()
+	rollback []rollbackHook
+	errs     []error
+	done     bool
+}
+
+// rollbackHook is a rollback hook. always is set once the nested transaction
+// that registered it has been rolled back, i.e. the hook runs even if the
+// outermost transaction is committed.
+type rollbackHook struct {
+	fn     func()
+	always bool
+}
+
+// finish runs the commit or rollback hooks in registration order. If the
+// transaction was committed, the rollback hooks of rolled back nested
+// transactions run before the commit hooks. Hooks only run once.
+func (h *txHooks) finish(committed bool) {
+	if h.done {
+		return
+	}
+
+	h.done = true
+
+	for _, hook := range h.rollback {
+		if hook.always || !committed {
+			h.call(hook.fn)
+		}
+	}
+
+	if committed {
+		for _, fn := range h.commit {
+			h.call(fn)
+		}
+	}
+
+	h.commit = nil
+	h.rollback = nil
+}
+
+// call runs fn and records a panic as an error.
+func (h *txHooks) call(fn func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			h.errs = append(h.errs, fmt.Errorf("transaction hook panicked: %v", p))
+		}
+	}()
+	fn()
+}
+
+// rollbackTo discards the commit hooks registered by a nested transaction.
+// Its rollback hooks are kept and run when the outermost transaction
+// finishes. The marks are the number of hooks when the nested transaction
+// began.
+func (h *txHooks) rollbackTo(commitMark, rollbackMark int) {
+	h.commit = h.commit[:commitMark]
+	for i := rollbackMark; i < len(h.rollback); i++ {
+		h.rollback[i].always = true
+	}
+}
+
+// OnCommit registers fn to run after the outermost transaction has been
+// committed, e.g. to publish events once the data is durable. Hooks run in
+// registration order. Hooks registered in a nested transaction that is rolled
+// back are discarded. If tx isn't in a transaction, fn runs immediately.
+func (tx *Tx) OnCommit(fn func()) {
+	if tx.wrapped == nil {
+		fn()
+		return
+	}
+	tx.hooks.commit = append(tx.hooks.commit, fn)
+}
+
+// OnRollback registers fn to run after the transaction has been rolled back.
+// For a nested transaction, fn runs when either it or the outermost
+// transaction is rolled back, but in both cases only once the outermost
+// transaction has finished. A failed commit counts as a rollback. If tx isn't
+// in a transaction, fn is never run.
+func (tx *Tx) OnRollback(fn func()) {
+	if tx.wrapped == nil {
+		return
+	}
+	tx.hooks.rollback = append(tx.hooks.rollback, rollbackHook{fn: fn})
+}
+
+// HookErrors returns the panics of hooks run so far as errors. Failing hooks
+// don't affect the result of Commit or Rollback, nor do they stop the
+// remaining hooks from running.
+func (tx *Tx) HookErrors() []error {
+	if tx.hooks == nil {
+		return nil
+	}
+	return tx.hooks.errs
+}
+
+// OnCommit registers fn to run after the transaction of c has been committed.
+// See Tx.OnCommit.
+func (c TXContext) OnCommit(fn func()) {
+	c.tx.OnCommit(fn)
+}
+
+// OnRollback registers fn to run after the transaction of c has been rolled
+// back. See Tx.OnRollback.
+func (c TXContext) OnRollback(fn func()) {
+	c.tx.OnRollback(fn)
+}
+
+// HookErrors returns the panics of hooks run so far as errors. See
+// Tx.HookErrors.
+func (c TXContext) HookErrors() []error {
+	return c.tx.HookErrors()
+}
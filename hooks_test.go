@@ -0,0 +1,89 @@
+package xl_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func TestHooks(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, updateSchema))
+
+	{
+		var calls []string
+		record := func(name string) func() {
+			return func() { calls = append(calls, name) }
+		}
+
+		tx, err := db.Beginxl()
+		require.Nil(t, err)
+		defer tx.Rollback()
+
+		tx.OnCommit(record("commit 1"))
+		tx.OnRollback(record("rollback 1"))
+
+		inner, err := tx.Beginxl()
+		require.Nil(t, err)
+		inner.OnCommit(func() { panic("boom") })
+		inner.OnCommit(record("commit 2"))
+		require.Nil(t, inner.Commit())
+
+		inner, err = tx.Beginxl()
+		require.Nil(t, err)
+		inner.OnCommit(record("commit 3"))
+		inner.OnRollback(record("rollback 3"))
+		require.Nil(t, inner.Rollback())
+		require.Equal(t, 0, len(calls))
+
+		require.Nil(t, tx.Commit())
+		require.Equal(t, []string{"rollback 3", "commit 1", "commit 2"}, calls)
+		require.Equal(t, 1, len(tx.HookErrors()))
+
+		require.NotNil(t, tx.Rollback())
+		require.Equal(t, []string{"rollback 3", "commit 1", "commit 2"}, calls)
+	}
+
+	{
+		var calls []string
+		fail := errors.New("fail")
+
+		err := db.Transact(context.Background(), nil, func(ctx xl.TXContext) error {
+			ctx.OnCommit(func() { calls = append(calls, "commit") })
+			return xl.RunInTx(ctx, nil, nil, func(ctx xl.TXContext) error {
+				ctx.OnRollback(func() { calls = append(calls, "rollback") })
+				return fail
+			})
+		})
+		require.Equal(t, fail, err)
+		require.Equal(t, []string{"rollback"}, calls)
+	}
+
+	{
+		var calls []string
+
+		err := db.Transact(context.Background(), nil, func(ctx xl.TXContext) error {
+			ctx.OnCommit(func() { calls = append(calls, "commit") })
+			ctx.OnRollback(func() { calls = append(calls, "rollback") })
+			return nil
+		})
+		require.Nil(t, err)
+		require.Equal(t, []string{"commit"}, calls)
+	}
+
+	{
+		ctx, err := xl.WithDB(context.Background(), db).Begin()
+		require.Nil(t, err)
+		ctx.OnCommit(func() { panic("boom") })
+		require.Nil(t, ctx.Commit())
+		require.Equal(t, 1, len(ctx.HookErrors()))
+		require.Equal(t, "transaction hook panicked: boom", ctx.HookErrors()[0].Error())
+	}
+}
@@ -0,0 +1,19 @@
+package xl
+
+import (
+	"net/http"
+)
+
+// Middleware returns HTTP middleware that attaches db to the context of each
+// request. Handlers get it back with FromContext.
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("/employees", listEmployees)
+//	http.ListenAndServe(":8080", xl.Middleware(db)(mux))
+func Middleware(db *DB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithDB(r.Context(), db)))
+		})
+	}
+}
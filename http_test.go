@@ -0,0 +1,41 @@
+package xl_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func TestMiddleware(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	handler := xl.Middleware(db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := xl.FromContext(r.Context())
+		if !ok {
+			http.Error(w, "no database", http.StatusInternalServerError)
+			return
+		}
+
+		var count int
+		if err := xl.Select("COUNT(*)").From("employee").First(ctx, &count); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte(strconv.Itoa(count)))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "5", w.Body.String())
+}
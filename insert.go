@@ -2,16 +2,25 @@ package xl
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type InsertQuery struct {
+	with      withClause
 	table     string
 	values    []NamedValue
+	rows      [][]NamedValue
+	columns   []string
+	source    *SelectQuery
+	conflict  conflictClause
 	returning string
+	err       error
 }
 
 func Insert(table string) *InsertQuery {
@@ -29,23 +38,281 @@ func (q *InsertQuery) Set(name string, param interface{}) {
 	q.values = append(q.values, namedParam{name, param})
 }
 
+// SetStruct sets a column for each field of the struct v as mapped by db
+// struct tags. See StructOptions for which fields are set. Combine with AddRow
+// to insert several structs.
+//
+//	q := xl.Insert("employee")
+//	q.SetStruct(&e, xl.StructOptions{})
+func (q *InsertQuery) SetStruct(v interface{}, opts StructOptions) {
+	values, err := structValues(v, opts)
+	if err != nil {
+		q.err = err
+		return
+	}
+	q.values = append(q.values, values...)
+}
+
+// AddRow completes the row built with Set and SetRaw and starts a new one.
+// All rows must set the same columns in the same order.
+//
+//	for _, e := range employees {
+//		q.Set("name", e.Name)
+//		q.Set("salary", e.Salary)
+//		q.AddRow()
+//	}
+func (q *InsertQuery) AddRow() {
+	if len(q.values) > 0 {
+		q.rows = append(q.rows, q.values)
+		q.values = make([]NamedValue, 0)
+	}
+}
+
+// Columns sets the columns of rows added with Values or returned by Select.
+func (q *InsertQuery) Columns(names ...string) *InsertQuery {
+	q.columns = names
+	return q
+}
+
+// Values adds a row with one parameter per column set with Columns.
+//
+//	q := xl.Insert("employee").Columns("name", "salary")
+//	q.Values("Alice Örn", 12000)
+//	q.Values("Bob Älv", 9000)
+func (q *InsertQuery) Values(params ...interface{}) *InsertQuery {
+	if len(params) != len(q.columns) {
+		q.err = fmt.Errorf("got %d values for %d columns", len(params), len(q.columns))
+		return q
+	}
+
+	q.AddRow()
+
+	row := make([]NamedValue, len(params))
+	for i := range params {
+		row[i] = namedParam{q.columns[i], params[i]}
+	}
+	q.rows = append(q.rows, row)
+
+	return q
+}
+
+// Select makes the query insert the rows returned by sq into the columns set
+// with Columns.
+//
+//	sq := xl.Select("name, salary").From("employee")
+//	sq.Where("updated<?", cutoff)
+//	q := xl.Insert("employee_archive").Columns("name", "salary").Select(sq)
+func (q *InsertQuery) Select(sq *SelectQuery) *InsertQuery {
+	q.source = sq
+	return q
+}
+
 func (q *InsertQuery) Returning(expr string) {
 	q.returning = expr
 }
 
+// With adds a common table expression. See SelectQuery.With.
+func (q *InsertQuery) With(name string, query Statementer) *InsertQuery {
+	q.with.add(name, query, false)
+	return q
+}
+
+// WithRecursive adds a common table expression and makes the WITH clause
+// RECURSIVE.
+func (q *InsertQuery) WithRecursive(name string, query Statementer) *InsertQuery {
+	q.with.add(name, query, true)
+	return q
+}
+
+// Statement builds a single INSERT statement for all rows. Use Statements to
+// split a large batch according to the bind parameter limit of the database.
 func (q *InsertQuery) Statement(d Dialect) (*Statement, error) {
-	if len(q.values) == 0 {
+	if q.source != nil {
+		return q.selectStatement(d)
+	}
+
+	rows, err := q.allRows()
+	if err != nil {
+		return nil, err
+	}
+	return q.statement(d, rows)
+}
+
+// Statements builds one or more INSERT statements for all rows, splitting the
+// rows so that no statement exceeds the bind parameter limit of the database.
+func (q *InsertQuery) Statements(d Dialect) ([]*Statement, error) {
+	if q.source != nil {
+		st, err := q.selectStatement(d)
+		if err != nil {
+			return nil, err
+		}
+		return []*Statement{st}, nil
+	}
+
+	rows, err := q.allRows()
+	if err != nil {
+		return nil, err
+	}
+
+	max := d.maxParams()
+
+	if max <= 0 {
+		st, err := q.statement(d, rows)
+		if err != nil {
+			return nil, err
+		}
+		return []*Statement{st}, nil
+	}
+
+	// Parameters of the WITH and ON CONFLICT clauses are repeated in every
+	// statement.
+	var scratch bytes.Buffer
+	withParams := make([]interface{}, 0)
+	if err := q.with.write(&scratch, &withParams, d); err != nil {
+		return nil, err
+	}
+	if err := q.conflict.write(&scratch, &withParams, d, rows[0][0].Name()); err != nil {
+		return nil, err
+	}
+
+	stmts := make([]*Statement, 0, 1)
+	start := 0
+	count := len(withParams)
+
+	for i := range rows {
+		n := countParams(rows[i])
+
+		if i > start && count+n > max {
+			st, err := q.statement(d, rows[start:i])
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, st)
+			start = i
+			count = len(withParams)
+		}
+
+		count += n
+	}
+
+	st, err := q.statement(d, rows[start:])
+	if err != nil {
+		return nil, err
+	}
+
+	return append(stmts, st), nil
+}
+
+// allRows returns the rows added with AddRow or Values and the row currently
+// built with Set and SetRaw. All rows must have the same columns.
+func (q *InsertQuery) allRows() ([][]NamedValue, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	rows := q.rows
+
+	if len(q.values) > 0 {
+		rows = append(rows[:len(rows):len(rows)], q.values)
+	}
+
+	if len(rows) == 0 {
 		return nil, fmt.Errorf("no values")
 	}
 
+	for i := 1; i < len(rows); i++ {
+		if len(rows[i]) != len(rows[0]) {
+			return nil, fmt.Errorf("row %d has %d columns, expected %d", i, len(rows[i]), len(rows[0]))
+		}
+		for j := range rows[i] {
+			if rows[i][j].Name() != rows[0][j].Name() {
+				return nil, fmt.Errorf("row %d has column %s, expected %s", i, rows[i][j].Name(), rows[0][j].Name())
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func (q *InsertQuery) statement(d Dialect, rows [][]NamedValue) (*Statement, error) {
 	var s bytes.Buffer
 	params := make([]interface{}, 0)
 
+	if err := q.with.write(&s, &params, d); err != nil {
+		return nil, err
+	}
+
 	s.WriteString("INSERT INTO " + q.table + " (")
-	writeInsertNames(&s, q.values)
-	s.WriteString(") VALUES (")
-	writeInsertValues(&s, &params, q.values)
-	s.WriteString(")")
+	writeInsertNames(&s, rows[0])
+	s.WriteString(") VALUES ")
+
+	for i := range rows {
+		if i > 0 {
+			s.WriteString(", ")
+		}
+		s.WriteString("(")
+		writeInsertValues(&s, &params, rows[i])
+		s.WriteString(")")
+	}
+
+	if err := q.conflict.write(&s, &params, d, rows[0][0].Name()); err != nil {
+		return nil, err
+	}
+
+	if q.returning != "" {
+		s.WriteString(" RETURNING " + q.returning)
+	}
+
+	query := s.String()
+
+	if d.BindType == sqlx.DOLLAR {
+		query = sqlx.Rebind(d.BindType, query)
+	}
+
+	return New(query, params...), nil
+}
+
+func (q *InsertQuery) selectStatement(d Dialect) (*Statement, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	if len(q.columns) == 0 {
+		return nil, errors.New("no columns")
+	}
+
+	if len(q.rows) > 0 || len(q.values) > 0 {
+		return nil, errors.New("values not allowed with Select")
+	}
+
+	sq := q.source
+
+	if len(sq.exprs) == 0 && len(sq.cols) == 0 {
+		return nil, errors.New("no columns in select")
+	}
+
+	if d.isSQLite() && q.conflict.action != conflictNone && len(sq.where) == 0 {
+		// SQLite would parse ON CONFLICT as a join constraint.
+		sq = sq.Clone()
+		sq.Where("1=1")
+	}
+
+	var s bytes.Buffer
+	params := make([]interface{}, 0)
+
+	if err := q.with.write(&s, &params, d); err != nil {
+		return nil, err
+	}
+
+	s.WriteString("INSERT INTO " + q.table + " (" + strings.Join(q.columns, ", ") + ") ")
+
+	if err := sq.writeSelect(&s, &params, d); err != nil {
+		return nil, err
+	}
+
+	if err := q.conflict.write(&s, &params, d, q.columns[0]); err != nil {
+		return nil, err
+	}
 
 	if q.returning != "" {
 		s.WriteString(" RETURNING " + q.returning)
@@ -60,6 +327,16 @@ func (q *InsertQuery) Statement(d Dialect) (*Statement, error) {
 	return New(query, params...), nil
 }
 
+func countParams(values []NamedValue) int {
+	n := 0
+	for i := range values {
+		if _, ok := values[i].(namedParam); ok {
+			n++
+		}
+	}
+	return n
+}
+
 func writeInsertNames(s *bytes.Buffer, values []NamedValue) {
 	for i := range values {
 		if i > 0 {
@@ -92,23 +369,89 @@ func writePlaceholders(s *bytes.Buffer, n int) {
 	}
 }
 
+// Exec executes the statements returned by Statements. The result holds the
+// total number of affected rows and the LastInsertId of the last statement as
+// reported by the driver. For a multi-row statement that id is driver-specific,
+// e.g. MySQL reports the id of the first row of the statement while SQLite
+// reports the id of the last row. Run it in a transaction if a failing batch
+// must not be partially inserted.
 func (q *InsertQuery) Exec(e Execer) (sql.Result, error) {
-	st, err := q.Statement(e.Dialect())
+	stmts, err := q.Statements(e.Dialect())
 	if err != nil {
 		return nil, err
 	}
-	return st.Exec(e)
+
+	var total batchResult
+
+	for _, st := range stmts {
+		result, err := st.Exec(e)
+		if err != nil {
+			return nil, err
+		}
+		if err := total.add(result); err != nil {
+			return nil, err
+		}
+	}
+
+	return total, nil
 }
 
-func (q *InsertQuery) ExecErr(e Execer) error {
-	st, err := q.Statement(e.Dialect())
+// ExecContext is like Exec but passes ctx to the database.
+func (q *InsertQuery) ExecContext(ctx context.Context, e Execer) (sql.Result, error) {
+	stmts, err := q.Statements(e.Dialect())
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = st.Exec(e)
+
+	var total batchResult
+
+	for _, st := range stmts {
+		result, err := st.ExecContext(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		if err := total.add(result); err != nil {
+			return nil, err
+		}
+	}
+
+	return total, nil
+}
+
+func (q *InsertQuery) ExecErr(e Execer) error {
+	_, err := q.Exec(e)
+	return err
+}
+
+func (q *InsertQuery) ExecErrContext(ctx context.Context, e Execer) error {
+	_, err := q.ExecContext(ctx, e)
 	return err
 }
 
+// ExecCount executes the insert and returns the number of inserted rows.
+func (q *InsertQuery) ExecCount(e Execer) (int64, error) {
+	result, err := q.Exec(e)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ExecCountContext is like ExecCount but passes ctx to the database.
+func (q *InsertQuery) ExecCountContext(ctx context.Context, e Execer) (int64, error) {
+	result, err := q.ExecContext(ctx, e)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ExecId executes the insert and returns the LastInsertId of the result. See
+// Exec for what it means for multi-row inserts.
 func (q *InsertQuery) ExecId(e Execer) (int64, error) {
 	result, err := q.Exec(e)
 
@@ -125,6 +468,22 @@ func (q *InsertQuery) ExecId(e Execer) (int64, error) {
 	return id, nil
 }
 
+func (q *InsertQuery) ExecIdContext(ctx context.Context, e Execer) (int64, error) {
+	result, err := q.ExecContext(ctx, e)
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
 func (q *InsertQuery) First(queryer Queryer, dest interface{}) error {
 	st, err := q.Statement(queryer.Dialect())
 	if err != nil {
@@ -132,3 +491,69 @@ func (q *InsertQuery) First(queryer Queryer, dest interface{}) error {
 	}
 	return st.First(queryer, dest)
 }
+
+func (q *InsertQuery) FirstContext(ctx context.Context, queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.FirstContext(ctx, queryer, dest)
+}
+
+// All executes the statements returned by Statements and appends the
+// RETURNING rows of each statement to dest.
+func (q *InsertQuery) All(queryer Queryer, dest interface{}) error {
+	stmts, err := q.Statements(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+
+	for _, st := range stmts {
+		if err := st.All(queryer, dest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AllContext is like All but passes ctx to the database.
+func (q *InsertQuery) AllContext(ctx context.Context, queryer Queryer, dest interface{}) error {
+	stmts, err := q.Statements(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+
+	for _, st := range stmts {
+		if err := st.AllContext(ctx, queryer, dest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// batchResult aggregates the results of the statements of a batch insert.
+type batchResult struct {
+	id       int64
+	idErr    error
+	affected int64
+}
+
+func (r *batchResult) add(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	r.affected += n
+	r.id, r.idErr = result.LastInsertId()
+	return nil
+}
+
+func (r batchResult) LastInsertId() (int64, error) {
+	return r.id, r.idErr
+}
+
+func (r batchResult) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
@@ -51,3 +51,133 @@ func TestReturning(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, "INSERT INTO employee (name) VALUES (?) RETURNING id", st.SQL)
 }
+
+func TestInsertBatch(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, insertSchema))
+
+	{
+		q := xl.Insert("employee")
+		q.SetRaw("updated", "current_timestamp")
+		q.Set("name", "Alice Örn")
+		q.Set("salary", 12000)
+		q.AddRow()
+		q.SetRaw("updated", "current_timestamp")
+		q.Set("name", "Bob Älv")
+		q.Set("salary", 9000)
+		st, err := q.Statement(db.Dialect())
+		require.Nil(t, err)
+		require.Equal(t, "INSERT INTO employee (updated, name, salary) VALUES (current_timestamp, ?, ?), (current_timestamp, ?, ?)", st.SQL)
+		require.Equal(t, []interface{}{"Alice Örn", 12000, "Bob Älv", 9000}, st.Params)
+
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(2), count)
+	}
+
+	{
+		q := xl.Insert("employee")
+		q.Set("name", "Alice Örn")
+		q.AddRow()
+		q.Set("salary", 9000)
+		_, err := q.Statement(db.Dialect())
+		require.NotNil(t, err)
+	}
+
+	{
+		q := xl.Insert("employee").Columns("name", "salary")
+		q.Values("Carol")
+		_, err := q.Statement(db.Dialect())
+		require.NotNil(t, err)
+	}
+
+	{
+		// 1000 rows with 3 parameters each exceeds the SQLite limit of 999.
+		q := xl.Insert("employee").Columns("updated", "name", "salary")
+		for i := 0; i < 1000; i++ {
+			q.Values("2019-01-01 00:00:00", "Employee", i)
+		}
+
+		stmts, err := q.Statements(db.Dialect())
+		require.Nil(t, err)
+		require.Equal(t, 4, len(stmts))
+		require.Equal(t, 999, len(stmts[0].Params))
+		require.Equal(t, 3, len(stmts[3].Params))
+
+		stmts, err = q.Statements(xl.Dialect{MaxParams: 300})
+		require.Nil(t, err)
+		require.Equal(t, 10, len(stmts))
+
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(1000), count)
+
+		var total int64
+		require.Nil(t, xl.Select("COUNT(*)").From("employee").First(db, &total))
+		require.Equal(t, int64(1002), total)
+	}
+}
+
+func TestInsertSelect(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, insertSchema))
+	require.Nil(t, xl.MultiExec(db, `
+create table archive (
+	id integer primary key,
+	name text not null,
+	salary integer not null
+);
+
+insert into employee (id, updated, name, salary) values (1, '2019-01-01', 'Alice Örn', 12000);
+insert into employee (id, updated, name, salary) values (2, '2019-01-01', 'Bob Älv', 9000);
+insert into employee (id, updated, name, salary) values (3, '2019-01-01', 'Carol Ek', 11000);
+`))
+
+	{
+		sq := xl.Select("id, name, salary").From("employee")
+		sq.Where("salary>?", 10000)
+		q := xl.Insert("archive").Columns("id", "name", "salary").Select(sq)
+		requireSQL(t, "INSERT INTO archive (id, name, salary) SELECT id, name, salary FROM employee WHERE salary>?", q)
+
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(2), count)
+	}
+
+	{
+		sq := xl.Select("id, name, salary*2").From("employee")
+		q := xl.Insert("archive").Columns("id", "name", "salary").Select(sq)
+		q.OnConflict("id").DoUpdate("salary")
+		st, err := q.Statement(db.Dialect())
+		require.Nil(t, err)
+		require.Equal(t, "INSERT INTO archive (id, name, salary) SELECT id, name, salary*2 FROM employee WHERE 1=1 ON CONFLICT (id) DO UPDATE SET salary=excluded.salary", st.SQL)
+		require.Nil(t, q.ExecErr(db))
+
+		var salaries []int64
+		sq = xl.Select("salary").From("archive")
+		sq.OrderBy("id")
+		require.Nil(t, sq.All(db, &salaries))
+		require.Equal(t, []int64{24000, 18000, 22000}, salaries)
+	}
+
+	{
+		sq := xl.Select("name").From("employee")
+		q := xl.Insert("archive").Columns("name").Select(sq)
+		q.Returning("id")
+		st, err := q.Statement(xl.Dialect{Driver: "postgres"})
+		require.Nil(t, err)
+		require.Equal(t, "INSERT INTO archive (name) SELECT name FROM employee RETURNING id", st.SQL)
+	}
+
+	{
+		q := xl.Insert("archive").Select(xl.Select("name").From("employee"))
+		_, err := q.Statement(db.Dialect())
+		require.NotNil(t, err)
+	}
+}
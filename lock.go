@@ -0,0 +1,95 @@
+package xl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// LockStrength is the strength of a row lock acquired by SELECT ... FOR.
+type LockStrength string
+
+const (
+	LockUpdate      LockStrength = "UPDATE"
+	LockNoKeyUpdate LockStrength = "NO KEY UPDATE"
+	LockShare       LockStrength = "SHARE"
+	LockKeyShare    LockStrength = "KEY SHARE"
+)
+
+type lockClause struct {
+	strength LockStrength
+	of       []string
+	wait     string
+}
+
+func (l lockClause) copy() lockClause {
+	l.of = copyStrings(l.of)
+	return l
+}
+
+func (l lockClause) write(s *bytes.Buffer, d Dialect) error {
+	if l.strength == "" {
+		if l.wait != "" {
+			return errors.New(l.wait + " requires a row lock")
+		}
+		return nil
+	}
+
+	if d.isSQLite() {
+		return fmt.Errorf("row locking not supported by %s", d.Driver)
+	}
+
+	if d.isMySQL() && (l.strength == LockNoKeyUpdate || l.strength == LockKeyShare) {
+		return fmt.Errorf("FOR %s not supported by %s", l.strength, d.Driver)
+	}
+
+	s.WriteString(" FOR " + string(l.strength))
+
+	if len(l.of) > 0 {
+		s.WriteString(" OF " + strings.Join(l.of, ", "))
+	}
+
+	if l.wait != "" {
+		s.WriteString(" " + l.wait)
+	}
+
+	return nil
+}
+
+// Lock adds a locking clause, e.g. FOR UPDATE. If tables are provided, only
+// rows from those tables are locked. Locking is rejected at Statement time on
+// databases that don't support it, such as SQLite.
+func (q *SelectQuery) Lock(strength LockStrength, tables ...string) *SelectQuery {
+	q.lock.strength = strength
+	q.lock.of = tables
+	return q
+}
+
+// ForUpdate is a shorthand for Lock(LockUpdate, tables...).
+func (q *SelectQuery) ForUpdate(tables ...string) *SelectQuery {
+	return q.Lock(LockUpdate, tables...)
+}
+
+// ForShare is a shorthand for Lock(LockShare, tables...).
+func (q *SelectQuery) ForShare(tables ...string) *SelectQuery {
+	return q.Lock(LockShare, tables...)
+}
+
+// NoWait makes the query fail rather than wait for locked rows.
+func (q *SelectQuery) NoWait() *SelectQuery {
+	q.lock.wait = "NOWAIT"
+	return q
+}
+
+// SkipLocked makes the query skip locked rows rather than wait for them.
+// Useful for job queues.
+//
+//	q := xl.Select("id").From("job")
+//	q.OrderBy("id")
+//	q.LimitOffset(1, 0)
+//	q.ForUpdate().SkipLocked()
+func (q *SelectQuery) SkipLocked() *SelectQuery {
+	q.lock.wait = "SKIP LOCKED"
+	return q
+}
@@ -0,0 +1,77 @@
+package xl_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func TestLock(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	postgres := xl.Dialect{BindType: sqlx.DOLLAR, Driver: "postgres"}
+	mysql := xl.Dialect{BindType: sqlx.QUESTION, Driver: "mysql"}
+
+	{
+		q := xl.Select("id").From("job")
+		q.Where("state=?", "pending")
+		q.OrderBy("id")
+		q.LimitOffset(1, 0)
+		q.ForUpdate().SkipLocked()
+
+		st, err := q.Statement(postgres)
+		require.Nil(t, err)
+		require.Equal(t, "SELECT id FROM job WHERE state=$1 ORDER BY id LIMIT 1 OFFSET 0 FOR UPDATE SKIP LOCKED", st.SQL)
+
+		st, err = q.Statement(mysql)
+		require.Nil(t, err)
+		require.Equal(t, "SELECT id FROM job WHERE state=? ORDER BY id LIMIT 1 OFFSET 0 FOR UPDATE SKIP LOCKED", st.SQL)
+	}
+
+	{
+		q := xl.Select("j.id").FromAs("job", "j")
+		q.FromAs("worker", "w")
+		q.ForShare("j").NoWait()
+		requireSQL(t, "SELECT j.id FROM job j, worker w FOR SHARE OF j NOWAIT", q)
+	}
+
+	{
+		q := xl.Select("id").From("job")
+		q.Lock(xl.LockNoKeyUpdate)
+		st, err := q.Statement(postgres)
+		require.Nil(t, err)
+		require.Equal(t, "SELECT id FROM job FOR NO KEY UPDATE", st.SQL)
+
+		_, err = q.Statement(mysql)
+		require.NotNil(t, err)
+	}
+
+	{
+		q := xl.Select("id").From("job")
+		q.SkipLocked()
+		_, err := q.Statement(postgres)
+		require.NotNil(t, err)
+	}
+
+	{
+		db, err := xl.Open("sqlite3", ":memory:")
+		require.Nil(t, err)
+		require.Nil(t, xl.MultiExec(db, selectSchema))
+
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		q.LimitOffset(2, 0)
+		q.ForUpdate()
+
+		_, err = q.Statement(db.Dialect())
+		require.NotNil(t, err)
+		require.NotNil(t, q.All(db, &ids))
+
+		total, err := q.Total(db)
+		require.Nil(t, err)
+		require.Equal(t, 5, total)
+	}
+}
@@ -0,0 +1,169 @@
+package xl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Nulls controls where NULL values are placed by an Order.
+type Nulls int
+
+const (
+	// NullsDefault leaves NULL placement to the database.
+	NullsDefault Nulls = iota
+	// NullsFirst sorts NULL values before non-NULL values.
+	NullsFirst
+	// NullsLast sorts NULL values after non-NULL values.
+	NullsLast
+)
+
+// An Order is a term of an ORDER BY clause.
+type Order struct {
+	Expr   string
+	Params []interface{}
+	Desc   bool
+	Nulls  Nulls
+}
+
+// Asc returns an ascending Order.
+func Asc(expr string, params ...interface{}) Order {
+	return Order{Expr: expr, Params: params}
+}
+
+// Desc returns a descending Order.
+func Desc(expr string, params ...interface{}) Order {
+	return Order{Expr: expr, Params: params, Desc: true}
+}
+
+// NullsFirst returns a copy of o that sorts NULL values first.
+func (o Order) NullsFirst() Order {
+	o.Nulls = NullsFirst
+	return o
+}
+
+// NullsLast returns a copy of o that sorts NULL values last.
+func (o Order) NullsLast() Order {
+	o.Nulls = NullsLast
+	return o
+}
+
+// write writes the ORDER BY term. NULLS FIRST/LAST is emulated with an extra
+// IS NULL term on databases that don't support it.
+func (o Order) write(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	emulate := d.isMySQL() || d.isSQLite()
+
+	if o.Nulls != NullsDefault && emulate {
+		// false sorts before true so "x IS NULL" puts NULLs last.
+		if err := writeExpr(s, params, o.Expr+" IS NULL", o.Params, d); err != nil {
+			return err
+		}
+		if o.Nulls == NullsFirst {
+			s.WriteString(" DESC")
+		}
+		s.WriteString(", ")
+	}
+
+	if err := writeExpr(s, params, o.Expr, o.Params, d); err != nil {
+		return err
+	}
+
+	if o.Desc {
+		s.WriteString(" DESC")
+	}
+
+	if !emulate {
+		switch o.Nulls {
+		case NullsFirst:
+			s.WriteString(" NULLS FIRST")
+		case NullsLast:
+			s.WriteString(" NULLS LAST")
+		}
+	}
+
+	return nil
+}
+
+func writeOrderBy(s *bytes.Buffer, params *[]interface{}, orders []Order, d Dialect) error {
+	for i := range orders {
+		if i == 0 {
+			s.WriteString(" ORDER BY ")
+		} else {
+			s.WriteString(", ")
+		}
+		if err := orders[i].write(s, params, d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyOrders(a []Order) []Order {
+	if a == nil {
+		return nil
+	}
+
+	b := make([]Order, len(a))
+
+	for i := range a {
+		b[i] = a[i]
+		b[i].Params = copyParams(a[i].Params)
+	}
+
+	return b
+}
+
+// ErrSortKey is returned by ParseSort for keys that are not allowed.
+var ErrSortKey = errors.New("invalid sort key")
+
+// ParseSort parses a comma separated list of sort keys as typically provided
+// by an HTTP client, e.g. "-created_at,name". A key prefixed with - sorts in
+// descending order and a key may be suffixed with ":nullsfirst" or
+// ":nullslast". Keys are mapped to SQL expressions with allowed. Keys that
+// aren't in allowed are rejected with an error wrapping ErrSortKey so no
+// untrusted input ends up in the query.
+func ParseSort(spec string, allowed map[string]string) ([]Order, error) {
+	orders := make([]Order, 0)
+
+	for _, key := range strings.Split(spec, ",") {
+		key = strings.TrimSpace(key)
+
+		if key == "" {
+			continue
+		}
+
+		var o Order
+
+		if strings.HasPrefix(key, "-") {
+			o.Desc = true
+			key = key[1:]
+		} else if strings.HasPrefix(key, "+") {
+			key = key[1:]
+		}
+
+		if i := strings.LastIndex(key, ":"); i >= 0 {
+			switch key[i+1:] {
+			case "nullsfirst":
+				o.Nulls = NullsFirst
+			case "nullslast":
+				o.Nulls = NullsLast
+			default:
+				return nil, fmt.Errorf("%w %q", ErrSortKey, key)
+			}
+			key = key[:i]
+		}
+
+		expr, ok := allowed[key]
+
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrSortKey, key)
+		}
+
+		o.Expr = expr
+		orders = append(orders, o)
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,101 @@
+package xl_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+const orderSchema = `
+create table task (
+	id integer primary key,
+	priority integer,
+	name text not null
+);
+
+insert into task (id, priority, name) values (1, 2, 'b');
+insert into task (id, priority, name) values (2, null, 'a');
+insert into task (id, priority, name) values (3, 1, 'c');
+insert into task (id, priority, name) values (4, 2, 'a');
+`
+
+func TestOrderBy(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, orderSchema))
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("task")
+		q.OrderByDesc("priority")
+		q.OrderBy("name")
+		requireSQL(t, "SELECT id FROM task ORDER BY priority DESC, name", q)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{4, 1, 3, 2}, ids)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("task")
+		q.AddOrder(xl.Desc("priority").NullsFirst(), xl.Asc("name"))
+		requireSQL(t, "SELECT id FROM task ORDER BY priority DESC NULLS FIRST, name", q)
+		st, err := q.Statement(db.Dialect())
+		require.Nil(t, err)
+		require.Equal(t, "SELECT id FROM task ORDER BY priority IS NULL DESC, priority DESC, name", st.SQL)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{2, 4, 1, 3}, ids)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("task")
+		q.AddOrder(xl.Asc("priority").NullsLast())
+		q.OrderBy("id")
+		st, err := q.Statement(xl.Dialect{Driver: "postgres"})
+		require.Nil(t, err)
+		require.Equal(t, "SELECT id FROM task ORDER BY priority NULLS LAST, id", st.SQL)
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{3, 1, 4, 2}, ids)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("task")
+		allowed := map[string]string{
+			"priority": "priority",
+			"name":     "name",
+		}
+		require.Nil(t, q.Sort("-priority:nullsfirst, name", allowed))
+		require.Nil(t, q.All(db, &ids))
+		require.Equal(t, []int64{2, 4, 1, 3}, ids)
+
+		err := q.Sort("id; DROP TABLE task", allowed)
+		require.True(t, errors.Is(err, xl.ErrSortKey))
+
+		_, err = xl.ParseSort("name:sideways", allowed)
+		require.True(t, errors.Is(err, xl.ErrSortKey))
+	}
+}
+
+func ExampleParseSort() {
+	orders, err := xl.ParseSort("-created_at,name", map[string]string{
+		"created_at": "e.created_at",
+		"name":       "e.name",
+	})
+	if err != nil {
+		panic(err)
+	}
+	q := xl.Select("*").From("employee e")
+	q.AddOrder(orders...)
+	st, _ := q.Statement(xl.Dialect{})
+	fmt.Println(st.SQL)
+
+	// Output:
+	// SELECT * FROM employee e ORDER BY e.created_at DESC, e.name
+}
@@ -0,0 +1,176 @@
+package xl
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// A RetryClassifier reports whether err is a transient error, such as a
+// serialization failure or a deadlock, after which a transaction may succeed
+// if retried.
+type RetryClassifier func(err error) bool
+
+// A RetryPolicy controls how RunInTx retries transactions that fail with a
+// transient error. See DB.SetRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a transaction is run.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. The delay doubles for
+	// each retry up to MaxDelay. A random jitter of up to half the delay is
+	// subtracted to avoid retrying in lockstep.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Classifier overrides the classifier registered for the driver.
+	Classifier RetryClassifier
+
+	// OnRetry, if set, is called before sleeping and retrying after attempt
+	// failed with err.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return d - time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// SetRetryPolicy makes RunInTx and DB.Transact retry transactions on this DB
+// according to p. Nested transactions are never retried on their own. nil
+// disables retries.
+func (db *DB) SetRetryPolicy(p *RetryPolicy) {
+	db.retry = p
+}
+
+var (
+	classifierMu sync.RWMutex
+	classifiers  = make(map[string]RetryClassifier)
+)
+
+// RegisterRetryClassifier sets the classifier used for driver, replacing the
+// built-in one if any. Built-in classifiers match SQLSTATE 40001
+// (serialization failure) and 40P01 (deadlock) for Postgres, error 1213
+// (deadlock) for MySQL and SQLITE_BUSY for SQLite.
+func RegisterRetryClassifier(driver string, fn RetryClassifier) {
+	classifierMu.Lock()
+	defer classifierMu.Unlock()
+	classifiers[driver] = fn
+}
+
+func (d Dialect) retryClassifier() RetryClassifier {
+	classifierMu.RLock()
+	fn, ok := classifiers[d.Driver]
+	classifierMu.RUnlock()
+
+	if ok {
+		return fn
+	}
+
+	switch {
+	case d.isPostgres():
+		return isPostgresRetryable
+	case d.isMySQL():
+		return isMySQLRetryable
+	case d.isSQLite():
+		return isSQLiteRetryable
+	}
+
+	return func(error) bool { return false }
+}
+
+// Driver errors are inspected by method or field name so that no driver has
+// to be imported.
+
+func isPostgresRetryable(err error) bool {
+	var s interface{ SQLState() string }
+	if errors.As(err, &s) {
+		return isRetryableState(s.SQLState())
+	}
+	if v, ok := errorField(err, "Code", reflect.String); ok {
+		return isRetryableState(v.String())
+	}
+	return false
+}
+
+func isRetryableState(state string) bool {
+	return state == "40001" || state == "40P01"
+}
+
+func isMySQLRetryable(err error) bool {
+	if v, ok := errorField(err, "Number", reflect.Uint16); ok {
+		return v.Uint() == 1213
+	}
+	return false
+}
+
+func isSQLiteRetryable(err error) bool {
+	if v, ok := errorField(err, "Code", reflect.Int); ok {
+		return v.Int() == 5
+	}
+	return false
+}
+
+// errorField returns the named field of the first error in the chain of err
+// that is a struct, or a pointer to a struct, with such a field of kind.
+func errorField(err error, name string, kind reflect.Kind) (reflect.Value, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.Indirect(reflect.ValueOf(err))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		if f := v.FieldByName(name); f.IsValid() && f.Kind() == kind {
+			return f, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// retry runs fn until it succeeds, fails with an error that isn't transient or
+// the attempts are exhausted.
+func (p *RetryPolicy) retry(ctx context.Context, d Dialect, fn func() error) error {
+	classify := p.Classifier
+
+	if classify == nil {
+		classify = d.retryClassifier()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+
+		if err == nil || attempt >= p.MaxAttempts || !classify(err) {
+			return err
+		}
+
+		delay := p.delay(attempt)
+
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+
+		t := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package xl_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+// fakeDriver fails statements with injected errors. A nil error lets the
+// statement succeed.
+type fakeDriver struct {
+	mu        sync.Mutex
+	errs      []error
+	execs     int
+	commits   int
+	rollbacks int
+}
+
+func (d *fakeDriver) inject(errs ...error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.errs = errs
+	d.execs, d.commits, d.rollbacks = 0, 0, 0
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{c.d}, nil
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{c.d}, nil
+}
+
+type fakeTx struct {
+	d *fakeDriver
+}
+
+func (tx fakeTx) Commit() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+	tx.d.commits++
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+	tx.d.rollbacks++
+	return nil
+}
+
+type fakeStmt struct {
+	d *fakeDriver
+}
+
+func (s fakeStmt) Close() error {
+	return nil
+}
+
+func (s fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.execs++
+	if len(s.d.errs) > 0 {
+		err := s.d.errs[0]
+		s.d.errs = s.d.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+// Errors shaped like those of the real drivers.
+
+type pgError struct {
+	Code string
+}
+
+func (e *pgError) Error() string {
+	return "pq: " + e.Code
+}
+
+type mysqlError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *mysqlError) Error() string {
+	return fmt.Sprintf("Error %d: %s", e.Number, e.Message)
+}
+
+type sqliteError struct {
+	Code int
+}
+
+func (e sqliteError) Error() string {
+	return "database is locked"
+}
+
+var fake = &fakeDriver{}
+
+func init() {
+	sql.Register("xlfake", fake)
+}
+
+func openFake(t *testing.T, driverName string) *xl.DB {
+	db, err := sql.Open("xlfake", "")
+	require.Nil(t, err)
+	return xl.NewDB(sqlx.NewDb(db, driverName))
+}
+
+func TestRetry(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	var retries []int
+
+	policy := &xl.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    2 * time.Millisecond,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			require.True(t, delay <= 2*time.Millisecond)
+			retries = append(retries, attempt)
+		},
+	}
+
+	update := func(ctx xl.TXContext) error {
+		q := xl.Update("employee")
+		q.Set("salary", 10000)
+		q.Where("id=?", 1)
+		return q.ExecOne(ctx)
+	}
+
+	tests := []struct {
+		driver string
+		err    error
+	}{
+		{"postgres", &pgError{"40001"}},
+		{"postgres", fmt.Errorf("update: %w", &pgError{"40P01"})},
+		{"mysql", &mysqlError{1213, "Deadlock found"}},
+		{"sqlite3", sqliteError{5}},
+	}
+
+	for _, test := range tests {
+		db := openFake(t, test.driver)
+		db.SetRetryPolicy(policy)
+
+		retries = nil
+		fake.inject(test.err)
+		require.Nil(t, db.Transact(context.Background(), nil, update))
+		require.Equal(t, []int{1}, retries)
+		require.Equal(t, 2, fake.execs)
+		require.Equal(t, 1, fake.commits)
+		require.Equal(t, 1, fake.rollbacks)
+
+		retries = nil
+		fake.inject(test.err, test.err, test.err)
+		require.Equal(t, test.err, db.Transact(context.Background(), nil, update))
+		require.Equal(t, []int{1, 2}, retries)
+		require.Equal(t, 3, fake.execs)
+		require.Equal(t, 0, fake.commits)
+	}
+
+	{
+		// Errors that aren't transient are not retried.
+		db := openFake(t, "postgres")
+		db.SetRetryPolicy(policy)
+
+		retries = nil
+		fake.inject(&pgError{"23505"})
+		require.NotNil(t, db.Transact(context.Background(), nil, update))
+		require.Nil(t, retries)
+		require.Equal(t, 1, fake.execs)
+	}
+
+	{
+		// Nested transactions are retried along with the outer transaction.
+		db := openFake(t, "postgres")
+		db.SetRetryPolicy(policy)
+
+		retries = nil
+		fake.inject(nil, nil, &pgError{"40001"})
+		err := db.Transact(context.Background(), nil, func(ctx xl.TXContext) error {
+			return xl.RunInTx(ctx, nil, nil, update)
+		})
+		require.Nil(t, err)
+		require.Equal(t, []int{1}, retries)
+	}
+
+	{
+		// Errors of unknown drivers are not retried.
+		db := openFake(t, "unknown")
+		db.SetRetryPolicy(policy)
+
+		retries = nil
+		fake.inject(errors.New("try again"))
+		require.NotNil(t, db.Transact(context.Background(), nil, update))
+		require.Nil(t, retries)
+	}
+
+	{
+		xl.RegisterRetryClassifier("fake", func(err error) bool {
+			return err.Error() == "try again"
+		})
+
+		db := openFake(t, "fake")
+		db.SetRetryPolicy(policy)
+
+		retries = nil
+		fake.inject(errors.New("try again"))
+		require.Nil(t, db.Transact(context.Background(), nil, update))
+		require.Equal(t, []int{1}, retries)
+	}
+}
@@ -0,0 +1,70 @@
+package xl
+
+import (
+	"context"
+	"errors"
+)
+
+// RunInTx runs fn in a transaction. The transaction is committed if fn returns
+// nil and rolled back if fn returns an error or panics, in which case the
+// panic is propagated after the rollback. If ctx carries an open
+// transaction, see FromContext, db is ignored and fn runs in a transaction
+// nested in it, so fn can fail without aborting the caller's transaction. See
+// Tx.BeginTxl for how opts applies to nested transactions. Otherwise fn runs
+// in a transaction of db, or of the database carried by ctx if db is nil.
+// Transactions that aren't nested are retried according to the retry policy
+// of the database, so fn must be safe to run more than once.
+//
+//	err := xl.RunInTx(ctx, db, nil, func(ctx xl.TXContext) error {
+//		q := xl.Update("account")
+//		q.SetRaw("balance", "balance-100")
+//		q.Where("id=?", id)
+//		return q.ExecOne(ctx)
+//	})
+func RunInTx(ctx context.Context, db *DB, opts *TxOptions, fn func(TXContext) error) error {
+	tc, ok := FromContext(ctx)
+
+	if !ok || (tc.tx.wrapped == nil && db != nil) {
+		if db == nil {
+			return errors.New("no database")
+		}
+		tc = WithDB(ctx, db)
+	}
+
+	policy := tc.DB().retry
+
+	if tc.tx.wrapped != nil || policy == nil {
+		return runInTx(tc, opts, fn)
+	}
+
+	return policy.retry(ctx, tc.DB().Dialect(), func() error {
+		return runInTx(tc, opts, fn)
+	})
+}
+
+func runInTx(tc TXContext, opts *TxOptions, fn func(TXContext) error) error {
+	tc, err := tc.BeginTx(opts)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tc.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tc); err != nil {
+		tc.Rollback()
+		return err
+	}
+
+	return tc.Commit()
+}
+
+// Transact is a shorthand for RunInTx(ctx, db, opts, fn).
+func (db *DB) Transact(ctx context.Context, opts *TxOptions, fn func(TXContext) error) error {
+	return RunInTx(ctx, db, opts, fn)
+}
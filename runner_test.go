@@ -0,0 +1,86 @@
+package xl_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func setSalary(ctx xl.TXContext, id int64, salary int) error {
+	q := xl.Update("employee")
+	q.Set("salary", salary)
+	q.Where("id=?", id)
+	return q.ExecOne(ctx)
+}
+
+func TestRunInTx(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, updateSchema))
+
+	ctx := context.Background()
+	fail := errors.New("fail")
+
+	{
+		err := xl.RunInTx(ctx, db, nil, func(ctx xl.TXContext) error {
+			return setSalary(ctx, 1, 13000)
+		})
+		require.Nil(t, err)
+		require.Equal(t, 13000, getSalary(t, db, 1))
+	}
+
+	{
+		err := db.Transact(ctx, nil, func(ctx xl.TXContext) error {
+			require.Nil(t, setSalary(ctx, 1, 14000))
+			return fail
+		})
+		require.Equal(t, fail, err)
+		require.Equal(t, 13000, getSalary(t, db, 1))
+	}
+
+	{
+		require.PanicsWithValue(t, "boom", func() {
+			db.Transact(ctx, nil, func(ctx xl.TXContext) error {
+				require.Nil(t, setSalary(ctx, 1, 14000))
+				panic("boom")
+			})
+		})
+		require.Equal(t, 13000, getSalary(t, db, 1))
+	}
+
+	{
+		err := db.Transact(ctx, nil, func(ctx xl.TXContext) error {
+			if err := setSalary(ctx, 1, 14000); err != nil {
+				return err
+			}
+
+			// Runs nested in the outer transaction.
+			err := xl.RunInTx(ctx, nil, nil, func(ctx xl.TXContext) error {
+				require.Nil(t, setSalary(ctx, 2, 10000))
+				return fail
+			})
+			require.Equal(t, fail, err)
+			require.Equal(t, 9000, getSalary(t, ctx, 2))
+
+			return xl.RunInTx(ctx, nil, nil, func(ctx xl.TXContext) error {
+				return setSalary(ctx, 2, 11000)
+			})
+		})
+		require.Nil(t, err)
+		require.Equal(t, 14000, getSalary(t, db, 1))
+		require.Equal(t, 11000, getSalary(t, db, 2))
+	}
+
+	{
+		err := xl.RunInTx(ctx, nil, nil, func(ctx xl.TXContext) error {
+			return nil
+		})
+		require.NotNil(t, err)
+	}
+}
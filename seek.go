@@ -0,0 +1,237 @@
+package xl
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+func init() {
+	gob.Register(time.Time{})
+}
+
+// A SeekKey is a column used for keyset pagination. Expr is the SQL
+// expression, e.g. "e.created_at". Name is the name of the column in the
+// result as mapped by db struct tags, e.g. "created_at". Name defaults to
+// Expr. Key columns must not be NULL and the last key must be unique.
+type SeekKey struct {
+	Expr string
+	Name string
+	Desc bool
+}
+
+func (k SeekKey) name() string {
+	if k.Name != "" {
+		return k.Name
+	}
+	return k.Expr
+}
+
+// A Cursor holds the key values of the last row of a page. Use Encode to pass
+// it to a client and ParseCursor to decode it again.
+type Cursor []interface{}
+
+// Encode returns the cursor as an opaque URL-safe string.
+func (c Cursor) Encode() (string, error) {
+	var b bytes.Buffer
+	values := []interface{}(c)
+	if err := gob.NewEncoder(&b).Encode(&values); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b.Bytes()), nil
+}
+
+// ParseCursor decodes a cursor returned by Cursor.Encode. An empty string
+// yields a nil Cursor, i.e. the first page.
+func ParseCursor(s string) (Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+
+	var values []interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+
+	return Cursor(values), nil
+}
+
+type seekClause struct {
+	keys  []SeekKey
+	after Cursor
+	limit int64
+}
+
+var errSeekOrderBy = errors.New("ORDER BY can't be combined with Seek")
+
+// orders returns the ORDER BY clause matching the keys.
+func (sc *seekClause) orders() []Order {
+	orders := make([]Order, len(sc.keys))
+
+	for i, k := range sc.keys {
+		orders[i] = Order{Expr: k.Expr, Desc: k.Desc}
+	}
+
+	return orders
+}
+
+// condition returns the predicate that selects rows after the cursor. Row
+// values are used if all keys have the same direction and the database
+// supports them. Otherwise the comparison is expanded to
+// a>? OR (a=? AND b>?) etc.
+func (sc *seekClause) condition(d Dialect) Condition {
+	desc := sc.keys[0].Desc
+	same := true
+
+	for _, k := range sc.keys {
+		if k.Desc != desc {
+			same = false
+		}
+	}
+
+	op := func(desc bool) string {
+		if desc {
+			return "<"
+		}
+		return ">"
+	}
+
+	if len(sc.keys) == 1 {
+		return Expr(sc.keys[0].Expr+op(desc)+"?", sc.after[0])
+	}
+
+	if same && (d.isPostgres() || d.isMySQL() || d.isSQLite()) {
+		var s bytes.Buffer
+		s.WriteString("(")
+		for i, k := range sc.keys {
+			if i > 0 {
+				s.WriteString(", ")
+			}
+			s.WriteString(k.Expr)
+		}
+		s.WriteString(")" + op(desc))
+		s.WriteString(Placeholders(len(sc.keys)))
+		return Expr(s.String(), sc.after...)
+	}
+
+	or := make([]Condition, len(sc.keys))
+
+	for i, k := range sc.keys {
+		and := make([]Condition, i+1)
+		for j := 0; j < i; j++ {
+			and[j] = Expr(sc.keys[j].Expr+"=?", sc.after[j])
+		}
+		and[i] = Expr(k.Expr+op(k.Desc)+"?", sc.after[i])
+		or[i] = And(and...)
+	}
+
+	return Or(or...)
+}
+
+// Seek sets up keyset pagination. The query is ordered by keys, limited to
+// limit rows and, unless after is nil, restricted to rows following after.
+// Use Page to fetch the rows along with the cursor of the next page. Calling
+// Seek again replaces the keys. The ORDER BY clause is owned by Seek, so the
+// query must not be ordered with OrderBy.
+//
+//	after, err := xl.ParseCursor(r.FormValue("after"))
+//	q := xl.Select("*").From("employee")
+//	err = q.Seek(after, 20, xl.SeekKey{Expr: "name"}, xl.SeekKey{Expr: "id"})
+//	next, err := q.Page(db, &employees)
+func (q *SelectQuery) Seek(after Cursor, limit int64, keys ...SeekKey) error {
+	if len(keys) == 0 {
+		return errors.New("no seek keys")
+	}
+
+	if after != nil && len(after) != len(keys) {
+		return fmt.Errorf("cursor has %d values, expected %d", len(after), len(keys))
+	}
+
+	if len(q.orderBy) > 0 {
+		return errSeekOrderBy
+	}
+
+	q.seek = &seekClause{keys, after, limit}
+	q.LimitOffset(limit, 0)
+
+	return nil
+}
+
+// Page runs a query set up with Seek and stores the rows in dest, which must
+// be a pointer to a slice. The returned cursor points at the last row and is
+// nil if there are no more pages.
+func (q *SelectQuery) Page(queryer Queryer, dest interface{}) (Cursor, error) {
+	if q.seek == nil {
+		return nil, errors.New("query not set up with Seek")
+	}
+
+	if err := q.All(queryer, dest); err != nil {
+		return nil, err
+	}
+
+	return q.nextCursor(dest)
+}
+
+// PageContext is like Page but passes ctx to the database.
+func (q *SelectQuery) PageContext(ctx context.Context, queryer Queryer, dest interface{}) (Cursor, error) {
+	if q.seek == nil {
+		return nil, errors.New("query not set up with Seek")
+	}
+
+	if err := q.AllContext(ctx, queryer, dest); err != nil {
+		return nil, err
+	}
+
+	return q.nextCursor(dest)
+}
+
+// nextCursor returns the cursor of the page fetched into dest.
+func (q *SelectQuery) nextCursor(dest interface{}) (Cursor, error) {
+	rows := reflect.Indirect(reflect.ValueOf(dest))
+
+	if rows.Kind() != reflect.Slice {
+		return nil, errors.New("dest must be a pointer to a slice")
+	}
+
+	if rows.Len() == 0 || int64(rows.Len()) < q.seek.limit {
+		return nil, nil
+	}
+
+	return cursorFromRow(rows.Index(rows.Len()-1), q.seek.keys)
+}
+
+func cursorFromRow(row reflect.Value, keys []SeekKey) (Cursor, error) {
+	row = reflect.Indirect(row)
+
+	if row.Kind() != reflect.Struct || row.Type() == reflect.TypeOf(time.Time{}) {
+		if len(keys) != 1 {
+			return nil, errors.New("multiple seek keys require a struct row")
+		}
+		return Cursor{row.Interface()}, nil
+	}
+
+	tm := mapper.TypeMap(row.Type())
+	c := make(Cursor, len(keys))
+
+	for i, k := range keys {
+		fi, ok := tm.Names[k.name()]
+		if !ok {
+			return nil, fmt.Errorf("seek key %q not found in row", k.name())
+		}
+		c[i] = reflectx.FieldByIndexesReadOnly(row, fi.Index).Interface()
+	}
+
+	return c, nil
+}
@@ -0,0 +1,126 @@
+package xl_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func TestSeek(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	type employee struct {
+		ID           int64  `db:"id"`
+		DepartmentID int64  `db:"department_id"`
+		Name         string `db:"name"`
+	}
+
+	keys := []xl.SeekKey{
+		{Expr: "e.department_id", Name: "department_id", Desc: true},
+		{Expr: "e.id", Name: "id"},
+	}
+
+	page := func(encoded string) ([]int64, string) {
+		after, err := xl.ParseCursor(encoded)
+		require.Nil(t, err)
+
+		var entries []employee
+		q := xl.Select("e.id, e.department_id, e.name").FromAs("employee", "e")
+		q.Where("e.salary>?", 8000)
+		require.Nil(t, q.Seek(after, 2, keys...))
+
+		next, err := q.Page(db, &entries)
+		require.Nil(t, err)
+
+		total, err := q.Total(db)
+		require.Nil(t, err)
+		require.Equal(t, 4, total)
+
+		ids := make([]int64, len(entries))
+		for i := range entries {
+			ids[i] = entries[i].ID
+		}
+
+		if next == nil {
+			return ids, ""
+		}
+
+		s, err := next.Encode()
+		require.Nil(t, err)
+		return ids, s
+	}
+
+	ids, cursor := page("")
+	require.Equal(t, []int64{2, 5}, ids)
+	require.NotEqual(t, "", cursor)
+
+	ids, cursor = page(cursor)
+	require.Equal(t, []int64{1, 3}, ids)
+	require.NotEqual(t, "", cursor)
+
+	ids, cursor = page(cursor)
+	require.Equal(t, 0, len(ids))
+	require.Equal(t, "", cursor)
+
+	{
+		q := xl.Select("id").From("employee")
+		require.Nil(t, q.Seek(xl.Cursor{int64(2), int64(5)}, 10, keys...))
+		requireSQL(t, "SELECT id FROM employee WHERE (e.department_id<? OR (e.department_id=? AND e.id>?)) ORDER BY e.department_id DESC, e.id LIMIT 10 OFFSET 0", q)
+	}
+
+	{
+		q := xl.Select("id").From("employee")
+		require.Nil(t, q.Seek(xl.Cursor{"Bob Älv", int64(2)}, 2, xl.SeekKey{Expr: "name"}, xl.SeekKey{Expr: "id"}))
+		st, err := q.Statement(db.Dialect())
+		require.Nil(t, err)
+		require.Equal(t, "SELECT id FROM employee WHERE (name, id)>(?, ?) ORDER BY name, id LIMIT 2 OFFSET 0", st.SQL)
+
+		var ids []int64
+		next, err := q.Page(db, &ids)
+		require.NotNil(t, err)
+		require.Nil(t, next)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		require.Nil(t, q.Seek(xl.Cursor{int64(2)}, 2, xl.SeekKey{Expr: "id"}))
+		next, err := q.Page(db, &ids)
+		require.Nil(t, err)
+		require.Equal(t, []int64{3, 4}, ids)
+		require.Equal(t, xl.Cursor{int64(4)}, next)
+	}
+
+	{
+		var ids []int64
+		q := xl.Select("id").From("employee")
+		require.Nil(t, q.Seek(nil, 2, xl.SeekKey{Expr: "id"}))
+		require.Nil(t, q.Seek(xl.Cursor{int64(4)}, 2, xl.SeekKey{Expr: "id", Desc: true}))
+		requireSQL(t, "SELECT id FROM employee WHERE id<? ORDER BY id DESC LIMIT 2 OFFSET 0", q)
+		next, err := q.Page(db, &ids)
+		require.Nil(t, err)
+		require.Equal(t, []int64{3, 2}, ids)
+		require.Equal(t, xl.Cursor{int64(2)}, next)
+
+		q.OrderBy("name")
+		_, err = q.Statement(xl.Dialect{})
+		require.NotNil(t, err)
+
+		q = xl.Select("id").From("employee")
+		q.OrderBy("name")
+		require.NotNil(t, q.Seek(nil, 2, xl.SeekKey{Expr: "id"}))
+	}
+
+	{
+		q := xl.Select("id").From("employee")
+		require.NotNil(t, q.Seek(xl.Cursor{1}, 2, keys...))
+		_, err := xl.ParseCursor("garbage!")
+		require.NotNil(t, err)
+	}
+}
@@ -2,22 +2,29 @@ package xl
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type SelectQuery struct {
+	with     withClause
 	distinct bool
-	exprs    []exprParams
+	exprs    []column
 	cols     []string
 	from     []tableAlias
 	joins    []tableJoin
-	where    []exprParams
-	orderBy  *exprParams
-	groupBy  string
+	where    []Condition
+	orderBy  []Order
+	groupBy  []string
+	having   []Condition
+	windows  []namedWindow
 	limit    *limitOffset
+	lock     lockClause
+	seek     *seekClause
 }
 
 func NewSelect() *SelectQuery {
@@ -86,26 +93,51 @@ func (q *SelectQuery) FromLateralSubselectAs(sq *SelectQuery, alias string) {
 	q.from = append(q.from, tableAlias{"", alias, sq, true})
 }
 
+// With adds a common table expression. name may include a column list, e.g.
+// "t(a, b)".
+//
+//	rich := xl.Select("*").From("employee")
+//	rich.Where("salary>?", 10000)
+//	q := xl.Select("name").From("rich")
+//	q.With("rich", rich)
+func (q *SelectQuery) With(name string, query Statementer) *SelectQuery {
+	q.with.add(name, query, false)
+	return q
+}
+
+// WithRecursive adds a common table expression and makes the WITH clause
+// RECURSIVE.
+func (q *SelectQuery) WithRecursive(name string, query Statementer) *SelectQuery {
+	q.with.add(name, query, true)
+	return q
+}
+
 func (q *SelectQuery) Distinct() {
 	q.distinct = true
 }
 
 func (q *SelectQuery) Column(expr string, params ...interface{}) {
 	if q.exprs == nil {
-		q.exprs = make([]exprParams, 0)
+		q.exprs = make([]column, 0)
 	}
 	q.exprs = append(q.exprs, exprParams{expr, params})
 }
 
 func (q *SelectQuery) Columns(exprs ...string) {
 	if q.exprs == nil {
-		q.exprs = make([]exprParams, 0)
+		q.exprs = make([]column, 0)
 	}
 	for _, expr := range exprs {
 		q.exprs = append(q.exprs, exprParams{expr, nil})
 	}
 }
 
+// ColumnSubselectAs adds a scalar subquery column named alias. Subqueries can
+// also be passed as parameters to Column, e.g. q.Column("? + 1", sq).
+func (q *SelectQuery) ColumnSubselectAs(sq *SelectQuery, alias string) {
+	q.Column("? \""+alias+"\"", sq)
+}
+
 func (q *SelectQuery) ColumnsAlias(columns ...string) {
 	if q.cols == nil {
 		q.cols = make([]string, 0, len(columns))
@@ -113,20 +145,61 @@ func (q *SelectQuery) ColumnsAlias(columns ...string) {
 	q.cols = append(q.cols, columns...)
 }
 
-// Where adds a WHERE clause. All WHERE clauses will be joined with AND. Note that Where doesn't surround the expression with parentheses.
-func (q *SelectQuery) Where(expr string, params ...interface{}) {
+// Where adds a WHERE clause. All WHERE clauses will be joined with AND. cond
+// is either an SQL expression with ? placeholders for params or a Condition
+// built with Expr, And, Or or Not. Note that Where doesn't surround an SQL
+// expression string with parentheses.
+func (q *SelectQuery) Where(cond interface{}, params ...interface{}) {
 	if q.where == nil {
-		q.where = make([]exprParams, 0)
+		q.where = make([]Condition, 0)
 	}
-	q.where = append(q.where, exprParams{expr, params})
+	q.where = append(q.where, toCondition(cond, params))
 }
 
-func (q *SelectQuery) GroupBy(expr string) {
-	q.groupBy = expr
+// GroupBy adds expressions to the GROUP BY clause.
+func (q *SelectQuery) GroupBy(exprs ...string) {
+	q.groupBy = append(q.groupBy, exprs...)
 }
 
+// Having adds a HAVING clause. Like Where, all HAVING clauses will be joined
+// with AND.
+func (q *SelectQuery) Having(cond interface{}, params ...interface{}) {
+	if q.having == nil {
+		q.having = make([]Condition, 0)
+	}
+	q.having = append(q.having, toCondition(cond, params))
+}
+
+// OrderBy appends expr to the ORDER BY clause. expr may include ASC or DESC.
 func (q *SelectQuery) OrderBy(expr string, params ...interface{}) {
-	q.orderBy = &exprParams{expr, params}
+	q.orderBy = append(q.orderBy, Asc(expr, params...))
+}
+
+// OrderByDesc appends expr in descending order to the ORDER BY clause.
+func (q *SelectQuery) OrderByDesc(expr string, params ...interface{}) {
+	q.orderBy = append(q.orderBy, Desc(expr, params...))
+}
+
+// AddOrder appends orders to the ORDER BY clause.
+//
+//	q.AddOrder(xl.Desc("updated").NullsLast(), xl.Asc("name"))
+func (q *SelectQuery) AddOrder(orders ...Order) {
+	q.orderBy = append(q.orderBy, orders...)
+}
+
+// Sort appends user-provided sort keys to the ORDER BY clause. See ParseSort.
+//
+//	err := q.Sort(r.FormValue("sort"), map[string]string{
+//		"created_at": "e.created_at",
+//		"name":       "e.name",
+//	})
+func (q *SelectQuery) Sort(spec string, allowed map[string]string) error {
+	orders, err := ParseSort(spec, allowed)
+	if err != nil {
+		return err
+	}
+	q.AddOrder(orders...)
+	return nil
 }
 
 func (q *SelectQuery) LimitOffset(limit, offset int64) *SelectQuery {
@@ -142,7 +215,9 @@ func (q *SelectQuery) Statement(d Dialect) (*Statement, error) {
 	var s bytes.Buffer
 	params := make([]interface{}, 0)
 
-	q.writeSelect(&s, &params)
+	if err := q.writeSelect(&s, &params, d); err != nil {
+		return nil, err
+	}
 
 	query := s.String()
 
@@ -153,17 +228,27 @@ func (q *SelectQuery) Statement(d Dialect) (*Statement, error) {
 	return New(query, params...), nil
 }
 
-func (q *SelectQuery) writeSelect(s *bytes.Buffer, params *[]interface{}) {
+func (q *SelectQuery) writeSelect(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	if err := q.with.write(s, params, d); err != nil {
+		return err
+	}
+
 	s.WriteString("SELECT ")
 
 	if q.distinct {
 		s.WriteString("DISTINCT ")
 	}
 
-	colCount := q.writeSelectColumns(s, params, 0)
+	colCount, err := q.writeSelectColumns(s, params, d, 0)
+
+	if err != nil {
+		return err
+	}
 
 	for _, j := range q.joins {
-		colCount = j.query.writeSelectColumns(s, params, colCount)
+		if colCount, err = j.query.writeSelectColumns(s, params, d, colCount); err != nil {
+			return err
+		}
 	}
 
 	if len(q.from) > 0 {
@@ -172,66 +257,136 @@ func (q *SelectQuery) writeSelect(s *bytes.Buffer, params *[]interface{}) {
 			if i > 0 {
 				s.WriteString(", ")
 			}
-			if table.subquery != nil {
-				if table.lateral {
-					s.WriteString("LATERAL ")
-				}
-				s.WriteString("(")
-				table.subquery.writeSelect(s, params)
-				s.WriteString(")")
-				if table.alias != "" {
-					s.WriteString(" " + table.alias)
-				}
-			} else {
-				s.WriteString(table.String())
+			if err := writeTable(s, params, table, d); err != nil {
+				return err
 			}
 		}
 	}
 
 	for _, j := range q.joins {
-		if len(j.query.from) > 0 {
-			table := j.query.from[0]
-			s.WriteString(" " + j.joinType + " " + table.String() + " ON " + j.cond)
-			*params = append(*params, j.params...)
+		if len(j.query.from) != 1 {
+			return errors.New("joined query must have exactly one FROM table")
+		}
+
+		s.WriteString(" " + j.joinType + " ")
+
+		if err := writeTable(s, params, j.query.from[0], d); err != nil {
+			return err
+		}
+
+		if len(j.using) > 0 {
+			s.WriteString(" USING (" + strings.Join(j.using, ", ") + ")")
+		} else if j.cond != "" {
+			s.WriteString(" ON ")
+			if err := writeExpr(s, params, j.cond, j.params, d); err != nil {
+				return err
+			}
 		}
 	}
 
-	whereCount := writeWhere(s, params, q.where, 0)
+	whereCount, err := writeWhere(s, params, q.where, d, 0)
+
+	if err != nil {
+		return err
+	}
+
+	if q.seek != nil && q.seek.after != nil {
+		if whereCount, err = writeWhere(s, params, []Condition{q.seek.condition(d)}, d, whereCount); err != nil {
+			return err
+		}
+	}
 
 	for _, j := range q.joins {
-		whereCount = writeWhere(s, params, j.query.where, whereCount)
+		if whereCount, err = writeWhere(s, params, j.query.where, d, whereCount); err != nil {
+			return err
+		}
+	}
+
+	if len(q.groupBy) > 0 {
+		s.WriteString(" GROUP BY " + strings.Join(q.groupBy, ", "))
 	}
 
-	if q.groupBy != "" {
-		s.WriteString(" GROUP BY " + q.groupBy)
+	if _, err := writeConditions(s, params, " HAVING ", q.having, d, 0); err != nil {
+		return err
 	}
 
-	if q.orderBy != nil {
-		s.WriteString(" ORDER BY " + q.orderBy.expr)
-		*params = append(*params, q.orderBy.params...)
+	if err := writeWindows(s, params, q.windows, d); err != nil {
+		return err
+	}
+
+	orders := q.orderBy
+
+	if q.seek != nil {
+		if len(q.orderBy) > 0 {
+			return errSeekOrderBy
+		}
+		orders = q.seek.orders()
+	}
+
+	if err := writeOrderBy(s, params, orders, d); err != nil {
+		return err
 	}
 
 	if q.limit != nil {
 		s.WriteString(fmt.Sprintf(" LIMIT %d OFFSET %d", q.limit.limit, q.limit.offset))
 	}
+
+	return q.lock.write(s, d)
 }
 
-func writeWhere(s *bytes.Buffer, params *[]interface{}, where []exprParams, count int) int {
-	for i := range where {
+func writeTable(s *bytes.Buffer, params *[]interface{}, table tableAlias, d Dialect) error {
+	if table.subquery == nil {
+		s.WriteString(table.String())
+		return nil
+	}
+
+	if table.lateral {
+		s.WriteString("LATERAL ")
+	}
+
+	s.WriteString("(")
+	if err := table.subquery.writeSelect(s, params, d); err != nil {
+		return err
+	}
+	s.WriteString(")")
+
+	if table.alias != "" {
+		s.WriteString(" " + table.alias)
+	}
+
+	return nil
+}
+
+func writeWhere(s *bytes.Buffer, params *[]interface{}, where []Condition, d Dialect, count int) (int, error) {
+	return writeConditions(s, params, " WHERE ", where, d, count)
+}
+
+func writeConditions(s *bytes.Buffer, params *[]interface{}, keyword string, conds []Condition, d Dialect, count int) (int, error) {
+	for i := range conds {
 		if count == 0 {
-			s.WriteString(" WHERE ")
+			s.WriteString(keyword)
 		} else {
 			s.WriteString(" AND ")
 		}
-		s.WriteString(where[i].expr)
-		*params = append(*params, where[i].params...)
+		if err := conds[i].writeCondition(s, params, d); err != nil {
+			return count, err
+		}
 		count++
 	}
 
-	return count
+	return count, nil
+}
+
+// column is an expression in the SELECT list.
+type column interface {
+	writeColumn(s *bytes.Buffer, params *[]interface{}, d Dialect) error
+}
+
+func (e exprParams) writeColumn(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	return writeExpr(s, params, e.expr, e.params, d)
 }
 
-func (q *SelectQuery) writeSelectColumns(s *bytes.Buffer, params *[]interface{}, count int) int {
+func (q *SelectQuery) writeSelectColumns(s *bytes.Buffer, params *[]interface{}, d Dialect, count int) (int, error) {
 	alias := ""
 
 	if len(q.from) > 0 {
@@ -255,12 +410,13 @@ func (q *SelectQuery) writeSelectColumns(s *bytes.Buffer, params *[]interface{},
 		if count > 0 {
 			s.WriteString(", ")
 		}
-		s.WriteString(q.exprs[i].expr)
-		*params = append(*params, q.exprs[i].params...)
+		if err := q.exprs[i].writeColumn(s, params, d); err != nil {
+			return count, err
+		}
 		count++
 	}
 
-	return count
+	return count, nil
 }
 
 func (q *SelectQuery) Queryx(queryer Queryer) (*sqlx.Rows, error) {
@@ -271,6 +427,14 @@ func (q *SelectQuery) Queryx(queryer Queryer) (*sqlx.Rows, error) {
 	return st.Queryx(queryer)
 }
 
+func (q *SelectQuery) QueryxContext(ctx context.Context, queryer Queryer) (*sqlx.Rows, error) {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	return st.QueryxContext(ctx, queryer)
+}
+
 func (q *SelectQuery) First(queryer Queryer, dest interface{}) error {
 	st, err := q.Statement(queryer.Dialect())
 	if err != nil {
@@ -279,6 +443,14 @@ func (q *SelectQuery) First(queryer Queryer, dest interface{}) error {
 	return st.First(queryer, dest)
 }
 
+func (q *SelectQuery) FirstContext(ctx context.Context, queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.FirstContext(ctx, queryer, dest)
+}
+
 func (q *SelectQuery) All(queryer Queryer, dest interface{}) error {
 	st, err := q.Statement(queryer.Dialect())
 	if err != nil {
@@ -287,16 +459,30 @@ func (q *SelectQuery) All(queryer Queryer, dest interface{}) error {
 	return st.All(queryer, dest)
 }
 
+func (q *SelectQuery) AllContext(ctx context.Context, queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.AllContext(ctx, queryer, dest)
+}
+
 func (q *SelectQuery) Clone() *SelectQuery {
 	cq := &SelectQuery{
-		exprs:   copyExprParams(q.exprs),
-		cols:    copyStrings(q.cols),
-		from:    copyTableAliases(q.from),
-		joins:   copyJoins(q.joins),
-		where:   copyExprParams(q.where),
-		orderBy: copyOrderBy(q.orderBy),
-		groupBy: q.groupBy,
-		limit:   copyLimitOffset(q.limit),
+		with:     q.with.copy(),
+		distinct: q.distinct,
+		exprs:    copyColumns(q.exprs),
+		cols:     copyStrings(q.cols),
+		from:     copyTableAliases(q.from),
+		joins:    copyJoins(q.joins),
+		where:    copyConditions(q.where),
+		orderBy:  copyOrders(q.orderBy),
+		groupBy:  copyStrings(q.groupBy),
+		having:   copyConditions(q.having),
+		windows:  copyWindows(q.windows),
+		limit:    copyLimitOffset(q.limit),
+		lock:     q.lock.copy(),
+		seek:     q.seek,
 	}
 
 	return cq
@@ -337,6 +523,7 @@ func copyJoins(a []tableJoin) []tableJoin {
 			joinType: a[i].joinType,
 			cond:     a[i].cond,
 			params:   copyParams(a[i].params),
+			using:    copyStrings(a[i].using),
 		}
 	}
 
@@ -354,25 +541,17 @@ func copyParams(a []interface{}) []interface{} {
 	return b
 }
 
-func copyExprParams(a []exprParams) []exprParams {
+func copyColumns(a []column) []column {
 	if a == nil {
 		return nil
 	}
 
-	b := make([]exprParams, len(a))
+	b := make([]column, len(a))
 	copy(b, a)
 
 	return b
 }
 
-func copyOrderBy(a *exprParams) *exprParams {
-	if a == nil {
-		return nil
-	}
-
-	return &exprParams{a.expr, copyParams(a.params)}
-}
-
 func copyLimitOffset(a *limitOffset) *limitOffset {
 	if a == nil {
 		return nil
@@ -381,32 +560,63 @@ func copyLimitOffset(a *limitOffset) *limitOffset {
 	return &limitOffset{a.limit, a.offset}
 }
 
-// Count runs this query without LIMIT/OFFSET and returns the COUNT.
+// Count runs this query without LIMIT/OFFSET, locking and keyset pagination
+// and returns the COUNT. Grouped and DISTINCT queries are wrapped in a
+// subquery so that groups rather than underlying rows are counted.
 func (q *SelectQuery) Total(queryer Queryer) (int, error) {
-	tq := q.Clone()
-	tq.cols = nil
-	tq.exprs = []exprParams{{"COUNT(*)", nil}}
-	tq.orderBy = nil
-	tq.groupBy = ""
-	tq.limit = nil
+	st, err := q.totalStatement(queryer.Dialect())
 
-	for i := range tq.joins {
-		tq.joins[i].query.cols = nil
-		tq.joins[i].query.exprs = nil
+	if err != nil {
+		return 0, err
 	}
 
-	st, err := tq.Statement(queryer.Dialect())
+	var count int
+	err = st.QueryRowx(queryer).Scan(&count)
+
+	return count, err
+}
+
+// TotalContext is like Total but passes ctx to the database.
+func (q *SelectQuery) TotalContext(ctx context.Context, queryer Queryer) (int, error) {
+	st, err := q.totalStatement(queryer.Dialect())
 
 	if err != nil {
 		return 0, err
 	}
 
 	var count int
-	err = st.QueryRowx(queryer).Scan(&count)
+	err = st.QueryRowxContext(ctx, queryer).Scan(&count)
 
 	return count, err
 }
 
+func (q *SelectQuery) totalStatement(d Dialect) (*Statement, error) {
+	tq := q.Clone()
+	tq.orderBy = nil
+	tq.limit = nil
+	tq.lock = lockClause{}
+	tq.seek = nil
+
+	if len(tq.groupBy) > 0 || len(tq.having) > 0 || tq.distinct {
+		cq := NewSelect()
+		cq.with = tq.with
+		tq.with = withClause{}
+		cq.Column("COUNT(*)")
+		cq.FromSubselectAs(tq, "t")
+		tq = cq
+	} else {
+		tq.cols = nil
+		tq.exprs = []column{exprParams{"COUNT(*)", nil}}
+
+		for i := range tq.joins {
+			tq.joins[i].query.cols = nil
+			tq.joins[i].query.exprs = nil
+		}
+	}
+
+	return tq.Statement(d)
+}
+
 func (q *SelectQuery) InnerJoin(jq *SelectQuery, cond string, params ...interface{}) {
 	q.Join(jq, "INNER JOIN", cond, params...)
 }
@@ -415,11 +625,38 @@ func (q *SelectQuery) LeftJoin(jq *SelectQuery, cond string, params ...interface
 	q.Join(jq, "LEFT JOIN", cond, params...)
 }
 
+func (q *SelectQuery) RightJoin(jq *SelectQuery, cond string, params ...interface{}) {
+	q.Join(jq, "RIGHT JOIN", cond, params...)
+}
+
+func (q *SelectQuery) FullJoin(jq *SelectQuery, cond string, params ...interface{}) {
+	q.Join(jq, "FULL JOIN", cond, params...)
+}
+
+// CrossJoin joins every row of jq. A LATERAL subselect in jq (see
+// FromLateralSubselectAs) may refer to columns of preceding tables.
+func (q *SelectQuery) CrossJoin(jq *SelectQuery) {
+	q.Join(jq, "CROSS JOIN", "")
+}
+
+// Join joins the FROM table of jq, which may be a (LATERAL) subselect. The
+// columns and WHERE clauses of jq are merged into this query. If cond is
+// empty, no ON clause is written.
 func (q *SelectQuery) Join(jq *SelectQuery, joinType, cond string, params ...interface{}) {
 	if q.joins == nil {
 		q.joins = make([]tableJoin, 0, 1)
 	}
-	q.joins = append(q.joins, tableJoin{jq, joinType, cond, params})
+	q.joins = append(q.joins, tableJoin{jq, joinType, cond, params, nil})
+}
+
+// JoinUsing is like Join but joins on equality of the given columns.
+//
+//	q.JoinUsing(jq, "INNER JOIN", "department_id")
+func (q *SelectQuery) JoinUsing(jq *SelectQuery, joinType string, cols ...string) {
+	if q.joins == nil {
+		q.joins = make([]tableJoin, 0, 1)
+	}
+	q.joins = append(q.joins, tableJoin{jq, joinType, "", nil, cols})
 }
 
 type tableJoin struct {
@@ -427,4 +664,5 @@ type tableJoin struct {
 	joinType string
 	cond     string
 	params   []interface{}
+	using    []string
 }
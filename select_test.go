@@ -214,6 +214,169 @@ func TestSelect(t *testing.T) {
 	}
 }
 
+func TestGroupByHaving(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	type result struct {
+		DepartmentID int64 `db:"department_id"`
+		Count        int64 `db:"count"`
+	}
+
+	{
+		var e []result
+		q := xl.Select(`department_id, COUNT(*) "count"`).From("employee")
+		q.Where("salary>?", 8000)
+		q.GroupBy("department_id")
+		q.Having("COUNT(*)>=?", 2)
+		q.Having("SUM(salary)>=?", 20000)
+		q.OrderBy("department_id")
+		requireSQL(t, `SELECT department_id, COUNT(*) "count" FROM employee WHERE salary>? GROUP BY department_id HAVING COUNT(*)>=? AND SUM(salary)>=? ORDER BY department_id`, q)
+		st, err := q.Statement(db.Dialect())
+		require.Nil(t, err)
+		require.Equal(t, []interface{}{8000, 2, 20000}, st.Params)
+		require.Nil(t, q.All(db, &e))
+		require.Equal(t, []result{{1, 2}, {2, 2}}, e)
+
+		total, err := q.Total(db)
+		require.Nil(t, err)
+		require.Equal(t, 2, total)
+	}
+
+	{
+		var e []result
+		q := xl.Select(`department_id, COUNT(*) "count"`).From("employee")
+		q.GroupBy("department_id")
+		q.GroupBy("salary>10000")
+		q.Having(xl.Or(xl.Expr("COUNT(*)>?", 1), xl.Expr("department_id=?", 1)))
+		q.OrderBy("department_id, 2")
+		requireSQL(t, `SELECT department_id, COUNT(*) "count" FROM employee GROUP BY department_id, salary>10000 HAVING (COUNT(*)>? OR department_id=?) ORDER BY department_id, 2`, q)
+		require.Nil(t, q.All(db, &e))
+		require.Equal(t, []result{{1, 1}, {1, 1}, {2, 2}}, e)
+
+		total, err := q.Total(db)
+		require.Nil(t, err)
+		require.Equal(t, 3, total)
+	}
+
+	{
+		q := xl.Select("department_id").From("employee")
+		q.Distinct()
+		total, err := q.Total(db)
+		require.Nil(t, err)
+		require.Equal(t, 2, total)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	{
+		iq := xl.SelectAlias("name")
+		iq.FromAs("department", "d")
+
+		q := xl.SelectAlias("name")
+		q.FromAs("employee", "e")
+		q.RightJoin(iq, "d.id=e.department_id")
+		requireSQL(t, `SELECT e.name "e.name", d.name "d.name" FROM employee e RIGHT JOIN department d ON d.id=e.department_id`, q)
+
+		q = xl.SelectAlias("name")
+		q.FromAs("employee", "e")
+		q.FullJoin(iq, "d.id=e.department_id AND d.city=?", "Stockholm")
+		requireSQL(t, `SELECT e.name "e.name", d.name "d.name" FROM employee e FULL JOIN department d ON d.id=e.department_id AND d.city=?`, q)
+	}
+
+	{
+		var count int
+		iq := xl.NewSelect()
+		iq.FromAs("department", "d")
+		iq.Where("d.city=?", "Stockholm")
+
+		q := xl.Select("COUNT(*)")
+		q.FromAs("employee", "e")
+		q.Where("e.salary>?", 9000)
+		q.CrossJoin(iq)
+		requireSQL(t, `SELECT COUNT(*) FROM employee e CROSS JOIN department d WHERE e.salary>? AND d.city=?`, q)
+		require.Nil(t, q.First(db, &count))
+		require.Equal(t, 3, count)
+	}
+
+	{
+		var names []string
+
+		sq := xl.Select("department_id AS id, name AS ename").From("employee")
+		sq.Where("salary>?", 10000)
+		jq := xl.NewSelect()
+		jq.FromSubselectAs(sq, "s")
+
+		q := xl.Select("s.ename")
+		q.From("department")
+		q.Where("city=?", "Hong Kong")
+		q.JoinUsing(jq, "INNER JOIN", "id")
+		requireSQL(t, `SELECT s.ename FROM department INNER JOIN (SELECT department_id AS id, name AS ename FROM employee WHERE salary>?) s USING (id) WHERE city=?`, q)
+		st, err := q.Statement(db.Dialect())
+		require.Nil(t, err)
+		require.Equal(t, []interface{}{10000, "Hong Kong"}, st.Params)
+		require.Nil(t, q.All(db, &names))
+		require.Equal(t, []string{"Eliza Yxa"}, names)
+	}
+
+	{
+		var e []struct {
+			Name  string `db:"name"`
+			Total int64  `db:"t.total"`
+		}
+
+		sq := xl.Select(`department_id, SUM(salary) "total"`).From("employee")
+		sq.Where("salary>?", 8000)
+		sq.GroupBy("department_id")
+
+		jq := xl.SelectAlias("total")
+		jq.FromSubselectAs(sq, "t")
+		jq.Where("t.total>?", 21000)
+
+		q := xl.Select("d.name")
+		q.FromAs("department", "d")
+		q.LeftJoin(jq, "t.department_id=d.id")
+		q.OrderBy("d.name")
+		requireSQL(t, `SELECT d.name, t.total "t.total" FROM department d LEFT JOIN (SELECT department_id, SUM(salary) "total" FROM employee WHERE salary>? GROUP BY department_id) t ON t.department_id=d.id WHERE t.total>? ORDER BY d.name`, q)
+		require.Nil(t, q.All(db, &e))
+		require.Equal(t, 1, len(e))
+		require.Equal(t, "HR", e[0].Name)
+		require.Equal(t, int64(22000), e[0].Total)
+	}
+
+	{
+		sq := xl.Select("e.name").FromAs("employee", "e")
+		sq.Where("e.department_id=d.id")
+		sq.OrderBy("e.salary DESC")
+		sq.LimitOffset(1, 0)
+
+		jq := xl.SelectAlias("name")
+		jq.FromLateralSubselectAs(sq, "top")
+
+		q := xl.Select("d.name")
+		q.FromAs("department", "d")
+		q.CrossJoin(jq)
+		requireSQL(t, `SELECT d.name, top.name "top.name" FROM department d CROSS JOIN LATERAL (SELECT e.name FROM employee e WHERE e.department_id=d.id ORDER BY e.salary DESC LIMIT 1 OFFSET 0) top`, q)
+	}
+
+	{
+		q := xl.Select("d.name")
+		q.FromAs("department", "d")
+		q.InnerJoin(xl.Select("1"), "1=1")
+		_, err := q.Statement(xl.Dialect{})
+		require.NotNil(t, err)
+	}
+}
+
 func ExampleSelectQuery_Where() {
 	q := xl.Select("salary").From("employee")
 	q.Where("name=?", "Alice Örn")
@@ -1,6 +1,7 @@
 package xl
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"time"
@@ -33,6 +34,15 @@ func (s *Statement) Exec(e Execer) (sql.Result, error) {
 	return result, err
 }
 
+// ExecContext is like Exec but passes ctx to the database.
+func (s *Statement) ExecContext(ctx context.Context, e Execer) (sql.Result, error) {
+	t0 := time.Now()
+	result, err := e.ExecContext(ctx, s.SQL, s.Params...)
+	t1 := time.Now()
+	logResult(s.SQL, s.Params, t1.Sub(t0), result, err)
+	return result, err
+}
+
 func (s *Statement) Queryx(q Queryer) (*sqlx.Rows, error) {
 	t0 := time.Now()
 	rows, err := q.Queryx(s.SQL, s.Params...)
@@ -41,6 +51,14 @@ func (s *Statement) Queryx(q Queryer) (*sqlx.Rows, error) {
 	return rows, err
 }
 
+func (s *Statement) QueryxContext(ctx context.Context, q Queryer) (*sqlx.Rows, error) {
+	t0 := time.Now()
+	rows, err := q.QueryxContext(ctx, s.SQL, s.Params...)
+	t1 := time.Now()
+	logResult(s.SQL, s.Params, t1.Sub(t0), nil, nil)
+	return rows, err
+}
+
 // Pass compiled SQL and parameters to sqlx.QueryRowx.
 func (s *Statement) QueryRowx(q Queryer) *sqlx.Row {
 	t0 := time.Now()
@@ -50,6 +68,15 @@ func (s *Statement) QueryRowx(q Queryer) *sqlx.Row {
 	return row
 }
 
+// Pass compiled SQL and parameters to sqlx.QueryRowxContext.
+func (s *Statement) QueryRowxContext(ctx context.Context, q Queryer) *sqlx.Row {
+	t0 := time.Now()
+	row := q.QueryRowxContext(ctx, s.SQL, s.Params...)
+	t1 := time.Now()
+	logResult(s.SQL, s.Params, t1.Sub(t0), nil, nil)
+	return row
+}
+
 // Pass compiled SQL and parameters to sqlx.Select.
 func (s *Statement) All(q Queryer, dest interface{}) error {
 	t0 := time.Now()
@@ -59,6 +86,15 @@ func (s *Statement) All(q Queryer, dest interface{}) error {
 	return err
 }
 
+// Pass compiled SQL and parameters to sqlx.SelectContext.
+func (s *Statement) AllContext(ctx context.Context, q Queryer, dest interface{}) error {
+	t0 := time.Now()
+	err := sqlx.SelectContext(ctx, q, dest, s.SQL, s.Params...)
+	t1 := time.Now()
+	logResult(s.SQL, s.Params, t1.Sub(t0), nil, err)
+	return err
+}
+
 // Pass compiled SQL and parameters to sqlx.Get.
 func (s *Statement) First(q Queryer, dest interface{}) error {
 	t0 := time.Now()
@@ -68,10 +104,26 @@ func (s *Statement) First(q Queryer, dest interface{}) error {
 	return err
 }
 
+// Pass compiled SQL and parameters to sqlx.GetContext.
+func (s *Statement) FirstContext(ctx context.Context, q Queryer, dest interface{}) error {
+	t0 := time.Now()
+	err := sqlx.GetContext(ctx, q, dest, s.SQL, s.Params...)
+	t1 := time.Now()
+	logResult(s.SQL, s.Params, t1.Sub(t0), nil, err)
+	return err
+}
+
 // Execute compiled statement and return affected rows.
 func (s *Statement) ExecCount(e Execer) (int64, error) {
-	result, err := s.Exec(e)
+	return affected(s.Exec(e))
+}
 
+// ExecCountContext is like ExecCount but passes ctx to the database.
+func (s *Statement) ExecCountContext(ctx context.Context, e Execer) (int64, error) {
+	return affected(s.ExecContext(ctx, e))
+}
+
+func affected(result sql.Result, err error) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
@@ -87,8 +139,15 @@ func (s *Statement) ExecCount(e Execer) (int64, error) {
 
 // Execute compiled statement and return error if affected rows is not exactly 1.
 func (s *Statement) ExecOne(e Execer) error {
-	count, err := s.ExecCount(e)
+	return exactlyOne(s.ExecCount(e))
+}
+
+// ExecOneContext is like ExecOne but passes ctx to the database.
+func (s *Statement) ExecOneContext(ctx context.Context, e Execer) error {
+	return exactlyOne(s.ExecCountContext(ctx, e))
+}
 
+func exactlyOne(count int64, err error) error {
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,98 @@
+package xl
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// mapper maps struct fields to columns the same way sqlx does when scanning.
+var mapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
+// StructOptions controls which fields SetStruct sets. Fields can also be
+// controlled with db tag options:
+//
+//	ID      int64     `db:"id,pk"`          // primary key, skipped
+//	Email   string    `db:"email,omitempty"` // skipped if zero
+//	Created time.Time `db:"created,readonly"` // always skipped
+type StructOptions struct {
+	// OmitZero skips all fields with zero values.
+	OmitZero bool
+
+	// Keys are primary key columns, in addition to fields tagged with pk.
+	Keys []string
+
+	// WithKeys sets primary key columns too. They are skipped by default.
+	WithKeys bool
+}
+
+func (o StructOptions) isKey(fi *reflectx.FieldInfo) bool {
+	if _, ok := fi.Options["pk"]; ok {
+		return true
+	}
+	for _, key := range o.Keys {
+		if key == fi.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// structValues returns a column value for each field of the struct v.
+// Fields of embedded structs are included in declaration order.
+func structValues(v interface{}, opts StructOptions) ([]NamedValue, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("SetStruct requires a struct")
+	}
+
+	values := make([]NamedValue, 0)
+	tm := mapper.TypeMap(rv.Type())
+	appendStructValues(&values, rv, tm.Tree, opts)
+
+	if len(values) == 0 {
+		return nil, errors.New("no fields to set")
+	}
+
+	return values, nil
+}
+
+func appendStructValues(values *[]NamedValue, rv reflect.Value, parent *reflectx.FieldInfo, opts StructOptions) {
+	for _, fi := range parent.Children {
+		if fi == nil {
+			continue
+		}
+
+		fv := rv.Field(fi.Index[len(fi.Index)-1])
+
+		if fi.Embedded {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				appendStructValues(values, fv, fi, opts)
+			}
+			continue
+		}
+
+		if _, ok := fi.Options["readonly"]; ok {
+			continue
+		}
+
+		if !opts.WithKeys && opts.isKey(fi) {
+			continue
+		}
+
+		if _, ok := fi.Options["omitempty"]; (ok || opts.OmitZero) && fv.IsZero() {
+			continue
+		}
+
+		*values = append(*values, namedParam{fi.Path, fv.Interface()})
+	}
+}
@@ -0,0 +1,103 @@
+package xl_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+const structSchema = `
+create table person (
+	id integer primary key,
+	created timestamp not null default current_timestamp,
+	updated timestamp,
+	name text not null,
+	email text not null default '',
+	nickname text
+);
+`
+
+type timestamps struct {
+	Created time.Time  `db:"created,readonly"`
+	Updated *time.Time `db:"updated"`
+}
+
+type person struct {
+	ID int64 `db:"id,pk"`
+	timestamps
+	Name     string  `db:"name"`
+	Email    string  `db:"email,omitempty"`
+	Nickname *string `db:"nickname"`
+	Ignored  string  `db:"-"`
+}
+
+func TestSetStruct(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, structSchema))
+
+	now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	nick := "Al"
+
+	{
+		p := person{Name: "Alice Örn", Nickname: &nick}
+		p.Updated = &now
+		q := xl.Insert("person")
+		q.SetStruct(&p, xl.StructOptions{})
+		requireSQL(t, "INSERT INTO person (updated, name, nickname) VALUES (?, ?, ?)", q)
+
+		id, err := q.ExecId(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(1), id)
+	}
+
+	{
+		p := person{ID: 2, Name: "Bob Älv", Email: "bob@example.com"}
+		q := xl.Insert("person")
+		q.SetStruct(p, xl.StructOptions{WithKeys: true})
+		requireSQL(t, "INSERT INTO person (id, updated, name, email, nickname) VALUES (?, ?, ?, ?, ?)", q)
+		require.Nil(t, q.ExecErr(db))
+	}
+
+	{
+		p := person{Name: "Alice Örn", Email: "alice@example.com"}
+		q := xl.Update("person")
+		q.SetStruct(&p, xl.StructOptions{OmitZero: true})
+		q.Where("id=?", 1)
+		requireSQL(t, "UPDATE person SET name=?, email=? WHERE id=?", q)
+		require.Nil(t, q.ExecErr(db))
+	}
+
+	{
+		var p person
+		q := xl.Select("*").From("person")
+		q.Where("id=?", 1)
+		require.Nil(t, q.First(db, &p))
+		require.Equal(t, "alice@example.com", p.Email)
+		require.Equal(t, "Al", *p.Nickname)
+		require.True(t, now.Equal(*p.Updated))
+		require.False(t, p.Created.IsZero())
+	}
+
+	{
+		q := xl.Update("person")
+		q.SetStruct(struct {
+			Key  string `db:"key"`
+			Name string `db:"name"`
+		}{"k", "n"}, xl.StructOptions{Keys: []string{"key"}})
+		q.Where("key=?", "k")
+		requireSQL(t, "UPDATE person SET name=? WHERE key=?", q)
+	}
+
+	{
+		q := xl.Update("person")
+		q.SetStruct("person", xl.StructOptions{})
+		_, err := q.Statement(db.Dialect())
+		require.NotNil(t, err)
+	}
+}
@@ -1,62 +1,300 @@
 package xl
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// TxOptions are options for starting a transaction.
+type TxOptions struct {
+	// Isolation is the isolation level. LevelDefault uses the default of the
+	// database.
+	Isolation sql.IsolationLevel
+
+	// ReadOnly makes the transaction read-only.
+	ReadOnly bool
+
+	// Timeout, if positive, makes the transaction roll back if it hasn't
+	// been committed within the duration.
+	Timeout time.Duration
+}
+
 // Wrapper type around sqlx.Tx that implements xl.Execer and xl.Queryer interfaces.
+// A Tx begun from another Tx is nested using a savepoint.
 type Tx struct {
 	db      *DB
 	wrapped *sqlx.Tx
-	inner   bool
-	innerOK bool
+
+	// Options of the outermost transaction. ctx and cancel are set if it has
+	// a timeout.
+	isolation sql.IsolationLevel
+	readOnly  bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	// Shared with nested transactions
+	hooks *txHooks
+	seq   *int
+
+	// For nested transactions
+	parent       *Tx
+	savepoint    string
+	done         bool
+	commitMark   int
+	rollbackMark int
 }
 
 func (tx *Tx) Dialect() Dialect {
-	return Dialect{
-		BindType: sqlx.BindType(tx.db.DriverName()),
-	}
+	return tx.db.Dialect()
+}
+
+// Isolation returns the isolation level the transaction was started with.
+// Nested transactions run at the level of the outermost transaction.
+func (tx *Tx) Isolation() sql.IsolationLevel {
+	return tx.isolation
+}
+
+// ReadOnly reports whether the transaction was started as read-only.
+func (tx *Tx) ReadOnly() bool {
+	return tx.readOnly
 }
 
+// Beginxl starts a transaction. If tx is already in a transaction, a nested
+// transaction is started with SAVEPOINT. Rolling back a nested transaction
+// only undoes the work done since it was started.
 func (tx *Tx) Beginxl() (*Tx, error) {
+	return tx.begin(context.Background(), nil)
+}
+
+// BeginTxl is like Beginxl but with a context and options. A nested
+// transaction runs with the options of the outermost transaction. Options it
+// can't honor, i.e. a stricter isolation level, a read-write transaction in a
+// read-only one or a timeout, are rejected with an error. Isolation levels
+// other than LevelDefault are only accepted if the outermost transaction was
+// started with an explicit level that is at least as strict.
+func (tx *Tx) BeginTxl(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	return tx.begin(ctx, opts)
+}
+
+// begin starts a transaction or, if tx is already in one, a nested
+// transaction.
+func (tx *Tx) begin(ctx context.Context, opts *TxOptions) (*Tx, error) {
 	if tx.wrapped != nil {
-		return &Tx{tx.db, tx.wrapped, true, false}, nil
+		if err := tx.compatible(opts); err != nil {
+			return nil, err
+		}
+
+		*tx.seq++
+		name := fmt.Sprintf("sp_%d", *tx.seq)
+
+		if _, err := New(tx.Dialect().savepoint(name)).ExecContext(ctx, tx); err != nil {
+			return nil, err
+		}
+
+		ntx := *tx
+		ntx.parent = tx
+		ntx.savepoint = name
+		ntx.done = false
+		ntx.cancel = nil
+		ntx.commitMark = len(tx.hooks.commit)
+		ntx.rollbackMark = len(tx.hooks.rollback)
+
+		return &ntx, nil
 	}
 
-	wrapped, err := tx.db.Beginx()
+	ntx := &Tx{db: tx.db, hooks: &txHooks{}, seq: new(int)}
+	var sqlOpts *sql.TxOptions
+
+	if opts != nil {
+		ntx.isolation = opts.Isolation
+		ntx.readOnly = opts.ReadOnly
+		sqlOpts = &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+
+		if opts.Timeout > 0 {
+			ctx, ntx.cancel = context.WithTimeout(ctx, opts.Timeout)
+			ntx.ctx = ctx
+		}
+	}
+
+	wrapped, err := tx.db.BeginTxx(ctx, sqlOpts)
 
 	if err != nil {
+		if ntx.cancel != nil {
+			ntx.cancel()
+		}
 		return nil, err
 	}
 
-	return &Tx{tx.db, wrapped, false, false}, nil
+	ntx.wrapped = wrapped
+
+	return ntx, nil
+}
+
+// weakerIsolation lists the isolation levels that each level is at least as
+// strict as. The levels aren't totally ordered, e.g. RepeatableRead and
+// Snapshot prevent different anomalies so neither covers the other.
+var weakerIsolation = map[sql.IsolationLevel][]sql.IsolationLevel{
+	sql.LevelReadUncommitted: {sql.LevelReadUncommitted},
+	sql.LevelReadCommitted:   {sql.LevelReadUncommitted, sql.LevelReadCommitted},
+	sql.LevelWriteCommitted:  {sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelWriteCommitted},
+	sql.LevelRepeatableRead:  {sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelWriteCommitted, sql.LevelRepeatableRead},
+	sql.LevelSnapshot:        {sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelSnapshot},
+	sql.LevelSerializable:    {sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelWriteCommitted, sql.LevelRepeatableRead, sql.LevelSnapshot, sql.LevelSerializable},
+	sql.LevelLinearizable:    {sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelWriteCommitted, sql.LevelRepeatableRead, sql.LevelSnapshot, sql.LevelSerializable, sql.LevelLinearizable},
+}
+
+// isolationCovers reports whether a transaction running at level outer
+// satisfies a request for level inner.
+func isolationCovers(outer, inner sql.IsolationLevel) bool {
+	for _, level := range weakerIsolation[outer] {
+		if level == inner {
+			return true
+		}
+	}
+	return false
+}
+
+// compatible returns an error if a transaction nested in tx can't run with
+// opts.
+func (tx *Tx) compatible(opts *TxOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	if opts.Isolation != sql.LevelDefault {
+		if !isolationCovers(tx.isolation, opts.Isolation) {
+			return fmt.Errorf("can't nest %s transaction in %s transaction", opts.Isolation, tx.isolation)
+		}
+	}
+
+	if !opts.ReadOnly && tx.readOnly {
+		return errors.New("can't nest read-write transaction in read-only transaction")
+	}
+
+	if opts.Timeout > 0 {
+		return errors.New("can't set timeout of nested transaction")
+	}
+
+	return nil
 }
 
+// Rollback aborts the transaction. For a nested transaction, it rolls back to
+// the savepoint and releases it. Calling Rollback after Commit is a no-op for
+// a nested transaction so it's safe to defer.
 func (tx *Tx) Rollback() error {
 	if tx.wrapped != nil {
-		if tx.innerOK {
+		if tx.savepoint != "" {
+			if tx.done {
+				return nil
+			}
+			tx.done = true
+			if _, err := New(tx.Dialect().rollbackTo(tx.savepoint)).Exec(tx); err != nil {
+				return err
+			}
+			tx.hooks.rollbackTo(tx.commitMark, tx.rollbackMark)
+			// ROLLBACK TO keeps the savepoint, so release it to not pile up
+			// savepoints in a long transaction.
+			if release := tx.Dialect().release(tx.savepoint); release != "" {
+				_, err := New(release).Exec(tx)
+				return err
+			}
 			return nil
 		}
-		return tx.wrapped.Rollback()
+		defer tx.release()
+		err := tx.wrapped.Rollback()
+		if err != sql.ErrTxDone {
+			tx.hooks.finish(false)
+		}
+		return err
 	}
 
 	return nil
 }
 
+// Commit commits the transaction. For a nested transaction, it releases the
+// savepoint and the work is committed along with the outer transaction.
 func (tx *Tx) Commit() error {
 	if tx.wrapped != nil {
-		if tx.inner {
-			tx.innerOK = true
+		if tx.savepoint != "" {
+			if tx.done {
+				return sql.ErrTxDone
+			}
+			tx.done = true
+			if release := tx.Dialect().release(tx.savepoint); release != "" {
+				_, err := New(release).Exec(tx)
+				return err
+			}
 			return nil
 		}
-		return tx.wrapped.Commit()
+		defer tx.release()
+		err := tx.wrapped.Commit()
+		tx.hooks.finish(err == nil)
+		return err
 	}
 
 	return nil
 }
 
+// finished reports whether tx has been committed or rolled back, either by
+// itself or along with the outermost transaction.
+func (tx *Tx) finished() bool {
+	return tx.wrapped != nil && (tx.done || tx.hooks.done)
+}
+
+// open returns tx or, if it has finished, its nearest unfinished outer
+// transaction. nil is returned if the outermost transaction has finished.
+func (tx *Tx) open() *Tx {
+	for ; tx != nil; tx = tx.parent {
+		if !tx.finished() {
+			return tx
+		}
+	}
+	return nil
+}
+
+// release releases the timeout of the outermost transaction.
+func (tx *Tx) release() {
+	if tx.cancel != nil {
+		tx.cancel()
+	}
+}
+
+func (d Dialect) isSQLServer() bool {
+	switch d.Driver {
+	case "sqlserver", "mssql":
+		return true
+	}
+	return false
+}
+
+func (d Dialect) savepoint(name string) string {
+	if d.isSQLServer() {
+		return "SAVE TRANSACTION " + name
+	}
+	return "SAVEPOINT " + name
+}
+
+func (d Dialect) rollbackTo(name string) string {
+	if d.isSQLServer() {
+		return "ROLLBACK TRANSACTION " + name
+	}
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+// release returns the statement that releases a savepoint. SQL Server has
+// none.
+func (d Dialect) release(name string) string {
+	if d.isSQLServer() {
+		return ""
+	}
+	return "RELEASE SAVEPOINT " + name
+}
+
 func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
 	if tx.wrapped != nil {
 		return tx.wrapped.Exec(query, args...)
@@ -64,6 +302,13 @@ func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
 	return tx.db.Exec(query, args...)
 }
 
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if tx.wrapped != nil {
+		return tx.wrapped.ExecContext(ctx, query, args...)
+	}
+	return tx.db.ExecContext(ctx, query, args...)
+}
+
 func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
 	if tx.wrapped != nil {
 		return tx.wrapped.Query(query, args...)
@@ -71,6 +316,13 @@ func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
 	return tx.db.Query(query, args...)
 }
 
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if tx.wrapped != nil {
+		return tx.wrapped.QueryContext(ctx, query, args...)
+	}
+	return tx.db.QueryContext(ctx, query, args...)
+}
+
 func (tx *Tx) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
 	if tx.wrapped != nil {
 		return tx.wrapped.Queryx(query, args...)
@@ -78,9 +330,23 @@ func (tx *Tx) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
 	return tx.db.Queryx(query, args...)
 }
 
+func (tx *Tx) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	if tx.wrapped != nil {
+		return tx.wrapped.QueryxContext(ctx, query, args...)
+	}
+	return tx.db.QueryxContext(ctx, query, args...)
+}
+
 func (tx *Tx) QueryRowx(query string, args ...interface{}) *sqlx.Row {
 	if tx.wrapped != nil {
 		return tx.wrapped.QueryRowx(query, args...)
 	}
 	return tx.db.QueryRowx(query, args...)
 }
+
+func (tx *Tx) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	if tx.wrapped != nil {
+		return tx.wrapped.QueryRowxContext(ctx, query, args...)
+	}
+	return tx.db.QueryRowxContext(ctx, query, args...)
+}
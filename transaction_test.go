@@ -0,0 +1,175 @@
+package xl_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func TestSavepoint(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, updateSchema))
+
+	tx, err := db.Beginxl()
+	require.Nil(t, err)
+	defer tx.Rollback()
+
+	setSalary := func(e xl.Execer, id int64, salary int) {
+		q := xl.Update("employee")
+		q.Set("salary", salary)
+		q.Where("id=?", id)
+		require.Nil(t, q.ExecOne(e))
+	}
+
+	setSalary(tx, 1, 13000)
+
+	{
+		// Inner failure leaves outer work intact.
+		inner, err := tx.Beginxl()
+		require.Nil(t, err)
+		setSalary(inner, 1, 14000)
+		setSalary(inner, 2, 10000)
+
+		q := xl.Insert("employee")
+		q.Set("id", 1)
+		q.SetRaw("updated", "current_timestamp")
+		q.Set("name", "Alice Örn")
+		q.Set("salary", 0)
+		require.NotNil(t, q.ExecErr(inner))
+
+		var queries []string
+		xl.SetLogger(func(query string, params []interface{}, d time.Duration, rows int64, err error) {
+			queries = append(queries, query)
+		})
+		require.Nil(t, inner.Rollback())
+		require.Nil(t, inner.Rollback())
+		xl.SetLogger(testlogger.Simple(t))
+		require.Equal(t, []string{"ROLLBACK TO SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1"}, queries)
+		require.Equal(t, 13000, getSalary(t, tx, 1))
+		require.Equal(t, 9000, getSalary(t, tx, 2))
+	}
+
+	{
+		inner, err := tx.Beginxl()
+		require.Nil(t, err)
+		setSalary(inner, 2, 10000)
+
+		innermost, err := inner.Beginxl()
+		require.Nil(t, err)
+		setSalary(innermost, 2, 11000)
+		require.Nil(t, innermost.Rollback())
+		require.Equal(t, 10000, getSalary(t, inner, 2))
+
+		innermost, err = inner.Beginxl()
+		require.Nil(t, err)
+		setSalary(innermost, 1, 15000)
+		require.Nil(t, innermost.Commit())
+
+		require.Nil(t, inner.Commit())
+		require.Nil(t, inner.Rollback())
+	}
+
+	require.Nil(t, tx.Commit())
+	require.Equal(t, 15000, getSalary(t, db, 1))
+	require.Equal(t, 10000, getSalary(t, db, 2))
+}
+
+func TestTxOptions(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, updateSchema))
+
+	{
+		tx, err := db.BeginTxl(context.Background(), &xl.TxOptions{Isolation: sql.LevelSerializable})
+		require.Nil(t, err)
+		require.Equal(t, sql.LevelSerializable, tx.Isolation())
+
+		inner, err := tx.BeginTxl(context.Background(), &xl.TxOptions{Isolation: sql.LevelReadCommitted})
+		require.Nil(t, err)
+		require.Equal(t, sql.LevelSerializable, inner.Isolation())
+		require.Nil(t, inner.Commit())
+
+		_, err = tx.BeginTxl(context.Background(), &xl.TxOptions{Timeout: time.Second})
+		require.NotNil(t, err)
+
+		require.Nil(t, tx.Rollback())
+	}
+
+	{
+		tx, err := db.Beginxl()
+		require.Nil(t, err)
+		require.Equal(t, sql.LevelDefault, tx.Isolation())
+
+		_, err = tx.BeginTxl(context.Background(), &xl.TxOptions{Isolation: sql.LevelSerializable})
+		require.NotNil(t, err)
+
+		require.Nil(t, tx.Rollback())
+	}
+
+	{
+		tx, err := db.BeginTxl(context.Background(), &xl.TxOptions{Isolation: sql.LevelSnapshot})
+		require.Nil(t, err)
+
+		_, err = tx.BeginTxl(context.Background(), &xl.TxOptions{Isolation: sql.LevelRepeatableRead})
+		require.NotNil(t, err)
+
+		inner, err := tx.BeginTxl(context.Background(), &xl.TxOptions{Isolation: sql.LevelReadCommitted})
+		require.Nil(t, err)
+		require.Nil(t, inner.Commit())
+
+		require.Nil(t, tx.Rollback())
+	}
+
+	{
+		ctx, err := xl.WithDB(context.Background(), db).BeginTx(&xl.TxOptions{ReadOnly: true})
+		require.Nil(t, err)
+		require.True(t, ctx.Tx().ReadOnly())
+
+		_, err = ctx.BeginTx(&xl.TxOptions{})
+		require.NotNil(t, err)
+
+		inner, err := ctx.BeginTx(&xl.TxOptions{ReadOnly: true})
+		require.Nil(t, err)
+		require.Nil(t, inner.Commit())
+
+		require.Nil(t, ctx.Rollback())
+	}
+
+	{
+		// The connection is discarded on timeout so use a file rather than
+		// an in-memory database.
+		dir, err := ioutil.TempDir("", "xl")
+		require.Nil(t, err)
+		defer os.RemoveAll(dir)
+
+		db, err := xl.Open("sqlite3", filepath.Join(dir, "test.db"))
+		require.Nil(t, err)
+		defer db.Close()
+		require.Nil(t, xl.MultiExec(db, updateSchema))
+
+		err = xl.RunInTx(context.Background(), db, &xl.TxOptions{Timeout: time.Millisecond}, func(ctx xl.TXContext) error {
+			_, ok := ctx.Deadline()
+			require.True(t, ok)
+			<-ctx.Done()
+			q := xl.Update("employee")
+			q.Set("salary", 0)
+			q.Where("id=?", 1)
+			return q.ExecErr(ctx)
+		})
+		require.Equal(t, context.DeadlineExceeded, err)
+		require.Equal(t, 12000, getSalary(t, db, 1))
+	}
+}
@@ -2,6 +2,7 @@ package xl
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -9,10 +10,14 @@ import (
 )
 
 type UpdateQuery struct {
+	with      withClause
 	table     string
 	values    []NamedValue
-	where     []exprParams
+	sources   []tableSource
+	where     []Condition
 	returning string
+	allRows   bool
+	err       error
 }
 
 func Update(table string) *UpdateQuery {
@@ -44,31 +49,137 @@ func (q *UpdateQuery) Set(name string, param interface{}) {
 	q.values = append(q.values, namedParam{name, param})
 }
 
-// Where adds a WHERE clause. All WHERE clauses will be joined with AND. Note
-// that Where doesn't surround the expression with parentheses. See SelectQuery
-// doc for example.
-func (q *UpdateQuery) Where(expr string, params ...interface{}) {
+// SetStruct sets a column for each field of the struct v as mapped by db
+// struct tags. See StructOptions for which fields are set.
+//
+//	q := xl.Update("employee")
+//	q.SetStruct(&e, xl.StructOptions{})
+//	q.Where("id=?", e.ID)
+func (q *UpdateQuery) SetStruct(v interface{}, opts StructOptions) {
+	values, err := structValues(v, opts)
+	if err != nil {
+		q.err = err
+		return
+	}
+	q.values = append(q.values, values...)
+}
+
+// From adds a table to update from. Its join condition goes in Where. See
+// Join for how it's rendered.
+func (q *UpdateQuery) From(table string) *UpdateQuery {
+	q.sources = append(q.sources, tableSource{table: tableAlias{name: table}})
+	return q
+}
+
+// FromAs adds a table with an alias to update from.
+func (q *UpdateQuery) FromAs(table, alias string) *UpdateQuery {
+	q.sources = append(q.sources, tableSource{table: tableAlias{name: table, alias: alias}})
+	return q
+}
+
+// FromSubselectAs adds a subquery to update from.
+func (q *UpdateQuery) FromSubselectAs(sq *SelectQuery, alias string) *UpdateQuery {
+	q.sources = append(q.sources, tableSource{table: tableAlias{subquery: sq, alias: alias}})
+	return q
+}
+
+// Join adds a table to update from along with its join condition. For MySQL
+// it's rendered as UPDATE t JOIN table ON cond SET ... and otherwise as
+// UPDATE t SET ... FROM table WHERE cond, which requires SQLite 3.33 or
+// later.
+//
+//	q := xl.Update("employee e")
+//	q.SetRaw("salary", "e.salary*r.factor")
+//	q.Join("raise r", "r.department_id=e.department_id")
+func (q *UpdateQuery) Join(table string, cond interface{}, params ...interface{}) *UpdateQuery {
+	q.sources = append(q.sources, tableSource{tableAlias{name: table}, toCondition(cond, params)})
+	return q
+}
+
+// JoinSubselectAs adds a subquery to update from along with its join
+// condition. See Join.
+func (q *UpdateQuery) JoinSubselectAs(sq *SelectQuery, alias string, cond interface{}, params ...interface{}) *UpdateQuery {
+	q.sources = append(q.sources, tableSource{tableAlias{subquery: sq, alias: alias}, toCondition(cond, params)})
+	return q
+}
+
+// Where adds a WHERE clause. All WHERE clauses will be joined with AND. cond
+// is either an SQL expression or a Condition. Note that Where doesn't surround
+// an SQL expression string with parentheses. See SelectQuery doc for example.
+func (q *UpdateQuery) Where(cond interface{}, params ...interface{}) {
 	if q.where == nil {
-		q.where = make([]exprParams, 0)
+		q.where = make([]Condition, 0)
 	}
-	q.where = append(q.where, exprParams{expr, params})
+	q.where = append(q.where, toCondition(cond, params))
 }
 
 func (q *UpdateQuery) Returning(expr string) {
 	q.returning = expr
 }
 
+// AllRows allows the query to update all rows. Without it, Statement returns
+// ErrNoWhere if there is no WHERE clause.
+func (q *UpdateQuery) AllRows() *UpdateQuery {
+	q.allRows = true
+	return q
+}
+
+// With adds a common table expression. See SelectQuery.With.
+func (q *UpdateQuery) With(name string, query Statementer) *UpdateQuery {
+	q.with.add(name, query, false)
+	return q
+}
+
+// WithRecursive adds a common table expression and makes the WITH clause
+// RECURSIVE.
+func (q *UpdateQuery) WithRecursive(name string, query Statementer) *UpdateQuery {
+	q.with.add(name, query, true)
+	return q
+}
+
 func (q *UpdateQuery) Statement(d Dialect) (*Statement, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
 	if len(q.values) == 0 {
 		return nil, fmt.Errorf("no values")
 	}
 
+	if !q.allRows && !hasFilter(q.where, q.sources) {
+		return nil, fmt.Errorf("%w in UPDATE %s", ErrNoWhere, q.table)
+	}
+
 	var s bytes.Buffer
 	params := make([]interface{}, 0)
 
-	s.WriteString("UPDATE " + q.table + " SET ")
+	if err := q.with.write(&s, &params, d); err != nil {
+		return nil, err
+	}
+
+	s.WriteString("UPDATE " + q.table)
+
+	if d.isMySQL() {
+		if err := writeJoinSources(&s, &params, q.sources, d); err != nil {
+			return nil, err
+		}
+	}
+
+	s.WriteString(" SET ")
 	writeUpdateValues(&s, &params, q.values)
-	writeWhere(&s, &params, q.where, 0)
+
+	count := 0
+
+	if !d.isMySQL() {
+		var err error
+		if count, err = writeListSources(&s, &params, " FROM ", q.sources, d); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := writeWhere(&s, &params, q.where, d, count); err != nil {
+		return nil, err
+	}
 
 	if q.returning != "" {
 		s.WriteString(" RETURNING " + q.returning)
@@ -83,6 +194,70 @@ func (q *UpdateQuery) Statement(d Dialect) (*Statement, error) {
 	return New(query, params...), nil
 }
 
+// tableSource is an additional table of UPDATE ... FROM or DELETE ... USING.
+// on is nil for tables added without a join condition.
+type tableSource struct {
+	table tableAlias
+	on    Condition
+}
+
+// writeJoinSources writes JOIN table ON cond for each joined table followed
+// by the tables without a join condition. Used for MySQL.
+func writeJoinSources(s *bytes.Buffer, params *[]interface{}, sources []tableSource, d Dialect) error {
+	for _, src := range sources {
+		if src.on != nil {
+			s.WriteString(" JOIN ")
+			if err := writeTable(s, params, src.table, d); err != nil {
+				return err
+			}
+			s.WriteString(" ON ")
+			if err := src.on.writeCondition(s, params, d); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, src := range sources {
+		if src.on == nil {
+			s.WriteString(", ")
+			if err := writeTable(s, params, src.table, d); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeListSources writes keyword followed by all tables and the join
+// conditions as the start of the WHERE clause. Returns the number of
+// conditions written.
+func writeListSources(s *bytes.Buffer, params *[]interface{}, keyword string, sources []tableSource, d Dialect) (int, error) {
+	for i, src := range sources {
+		if i == 0 {
+			s.WriteString(keyword)
+		} else {
+			s.WriteString(", ")
+		}
+		if err := writeTable(s, params, src.table, d); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+
+	for _, src := range sources {
+		if src.on != nil {
+			var err error
+			if count, err = writeConditions(s, params, " WHERE ", []Condition{src.on}, d, count); err != nil {
+				return count, err
+			}
+		}
+	}
+
+	return count, nil
+}
+
 func writeUpdateValues(s *bytes.Buffer, params *[]interface{}, values []NamedValue) {
 	for i := range values {
 		if i > 0 {
@@ -102,7 +277,15 @@ func (q *UpdateQuery) Exec(e Execer) (sql.Result, error) {
 	if err != nil {
 		return nil, err
 	}
-	return st.Exec(e)
+	return st.Exec(limitRows(e))
+}
+
+func (q *UpdateQuery) ExecContext(ctx context.Context, e Execer) (sql.Result, error) {
+	st, err := q.Statement(e.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	return st.ExecContext(ctx, limitRows(e))
 }
 
 func (q *UpdateQuery) ExecErr(e Execer) error {
@@ -110,7 +293,16 @@ func (q *UpdateQuery) ExecErr(e Execer) error {
 	if err != nil {
 		return err
 	}
-	_, err = st.Exec(e)
+	_, err = st.Exec(limitRows(e))
+	return err
+}
+
+func (q *UpdateQuery) ExecErrContext(ctx context.Context, e Execer) error {
+	st, err := q.Statement(e.Dialect())
+	if err != nil {
+		return err
+	}
+	_, err = st.ExecContext(ctx, limitRows(e))
 	return err
 }
 
@@ -119,7 +311,15 @@ func (q *UpdateQuery) ExecCount(e Execer) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return st.ExecCount(e)
+	return st.ExecCount(limitRows(e))
+}
+
+func (q *UpdateQuery) ExecCountContext(ctx context.Context, e Execer) (int64, error) {
+	st, err := q.Statement(e.Dialect())
+	if err != nil {
+		return 0, err
+	}
+	return st.ExecCountContext(ctx, limitRows(e))
 }
 
 func (q *UpdateQuery) ExecOne(e Execer) error {
@@ -127,7 +327,15 @@ func (q *UpdateQuery) ExecOne(e Execer) error {
 	if err != nil {
 		return err
 	}
-	return st.ExecOne(e)
+	return st.ExecOne(limitRows(e))
+}
+
+func (q *UpdateQuery) ExecOneContext(ctx context.Context, e Execer) error {
+	st, err := q.Statement(e.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.ExecOneContext(ctx, limitRows(e))
 }
 
 func (q *UpdateQuery) First(queryer Queryer, dest interface{}) error {
@@ -137,3 +345,11 @@ func (q *UpdateQuery) First(queryer Queryer, dest interface{}) error {
 	}
 	return st.First(queryer, dest)
 }
+
+func (q *UpdateQuery) FirstContext(ctx context.Context, queryer Queryer, dest interface{}) error {
+	st, err := q.Statement(queryer.Dialect())
+	if err != nil {
+		return err
+	}
+	return st.FirstContext(ctx, queryer, dest)
+}
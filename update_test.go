@@ -3,6 +3,7 @@ package xl_test
 import (
 	"testing"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/require"
 	"github.com/tomyl/xl"
 	"github.com/tomyl/xl/testlogger"
@@ -38,6 +39,73 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateFrom(t *testing.T) {
+	{
+		q := xl.Update("employee e")
+		q.SetRaw("salary", "e.salary*r.factor")
+		q.Join("raise r", "r.employee_id=e.id")
+		q.Where("r.year=?", 2019)
+
+		requireSQL(t, "UPDATE employee e SET salary=e.salary*r.factor FROM raise r WHERE r.employee_id=e.id AND r.year=?", q)
+
+		st, err := q.Statement(xl.Dialect{Driver: "mysql"})
+		require.Nil(t, err)
+		require.Equal(t, "UPDATE employee e JOIN raise r ON r.employee_id=e.id SET salary=e.salary*r.factor WHERE r.year=?", st.SQL)
+	}
+
+	{
+		sq := xl.Select("employee_id, MAX(factor) factor").From("raise")
+		sq.Where("year=?", 2019)
+		sq.GroupBy("employee_id")
+
+		q := xl.Update("employee e")
+		q.Set("updated", "2019-12-31")
+		q.SetRaw("salary", "e.salary*r.factor")
+		q.JoinSubselectAs(sq, "r", "r.employee_id=e.id")
+		q.From("department d")
+		q.Where("d.id=e.department_id AND d.name=?", "Sales")
+
+		st, err := q.Statement(xl.Dialect{Driver: "postgres", BindType: sqlx.DOLLAR})
+		require.Nil(t, err)
+		require.Equal(t, "UPDATE employee e SET updated=$1, salary=e.salary*r.factor FROM (SELECT employee_id, MAX(factor) factor FROM raise WHERE year=$2 GROUP BY employee_id) r, department d WHERE r.employee_id=e.id AND d.id=e.department_id AND d.name=$3", st.SQL)
+		require.Equal(t, []interface{}{"2019-12-31", 2019, "Sales"}, st.Params)
+
+		st, err = q.Statement(xl.Dialect{Driver: "mysql"})
+		require.Nil(t, err)
+		require.Equal(t, "UPDATE employee e JOIN (SELECT employee_id, MAX(factor) factor FROM raise WHERE year=? GROUP BY employee_id) r ON r.employee_id=e.id, department d SET updated=?, salary=e.salary*r.factor WHERE d.id=e.department_id AND d.name=?", st.SQL)
+		require.Equal(t, []interface{}{2019, "2019-12-31", "Sales"}, st.Params)
+	}
+
+	{
+		xl.SetLogger(testlogger.Simple(t))
+
+		db, err := xl.Connect("sqlite3", ":memory:")
+		require.Nil(t, err)
+		require.Nil(t, xl.MultiExec(db, updateSchema))
+		require.Nil(t, xl.MultiExec(db, `
+create table raise (employee_id integer not null, year integer not null, factor integer not null);
+insert into raise (employee_id, year, factor) values (1, 2018, 3);
+insert into raise (employee_id, year, factor) values (2, 2019, 2);
+`))
+
+		sq := xl.Select("employee_id, MAX(factor) factor").From("raise")
+		sq.Where("year=?", 2019)
+		sq.GroupBy("employee_id")
+
+		q := xl.Update("employee AS e")
+		q.SetRaw("salary", "e.salary*r.factor")
+		q.JoinSubselectAs(sq, "r", "r.employee_id=e.id")
+		q.Where("e.salary<?", 10000)
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(1), count)
+
+		var salaries []int64
+		require.Nil(t, db.Select(&salaries, "SELECT salary FROM employee ORDER BY id"))
+		require.Equal(t, []int64{12000, 18000}, salaries)
+	}
+}
+
 func TestTransactionCommit(t *testing.T) {
 	xl.SetLogger(testlogger.Simple(t))
 
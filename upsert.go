@@ -0,0 +1,190 @@
+package xl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type conflictAction int
+
+const (
+	conflictNone conflictAction = iota
+	conflictNothing
+	conflictUpdate
+)
+
+type conflictClause struct {
+	target     []string
+	constraint string
+	action     conflictAction
+	set        []NamedValue
+	where      []Condition
+}
+
+// OnConflict sets the conflict target of an upsert to a list of columns with
+// a unique index. Follow it with DoNothing or DoUpdate. MySQL ignores the
+// target since ON DUPLICATE KEY UPDATE applies to any unique key.
+//
+//	q := xl.Insert("employee")
+//	q.Set("email", email)
+//	q.Set("name", name)
+//	q.OnConflict("email").DoUpdate("name")
+func (q *InsertQuery) OnConflict(cols ...string) *InsertQuery {
+	q.conflict.target = cols
+	q.conflict.constraint = ""
+	return q
+}
+
+// OnConstraint sets the conflict target of an upsert to a named constraint.
+// Only supported by Postgres.
+func (q *InsertQuery) OnConstraint(name string) *InsertQuery {
+	q.conflict.constraint = name
+	q.conflict.target = nil
+	return q
+}
+
+// DoNothing makes the insert skip rows that conflict with existing rows.
+func (q *InsertQuery) DoNothing() *InsertQuery {
+	q.conflict.action = conflictNothing
+	q.conflict.set = nil
+	return q
+}
+
+// DoUpdate makes the insert update cols of existing rows with the values that
+// were proposed for insertion, i.e. col = excluded.col.
+func (q *InsertQuery) DoUpdate(cols ...string) *InsertQuery {
+	q.conflict.action = conflictUpdate
+	for _, col := range cols {
+		q.conflict.set = append(q.conflict.set, excludedValue{col})
+	}
+	return q
+}
+
+// DoUpdateSet makes the insert set column to param in existing rows.
+func (q *InsertQuery) DoUpdateSet(name string, param interface{}) *InsertQuery {
+	q.conflict.action = conflictUpdate
+	q.conflict.set = append(q.conflict.set, namedParam{name, param})
+	return q
+}
+
+// DoUpdateSetRaw makes the insert set column to an SQL expression in existing
+// rows. The expression is not translated between dialects, e.g. use
+// excluded.col for Postgres and SQLite but VALUES(col) for MySQL.
+func (q *InsertQuery) DoUpdateSetRaw(name, rawvalue string) *InsertQuery {
+	q.conflict.action = conflictUpdate
+	q.conflict.set = append(q.conflict.set, namedValue{name, rawvalue})
+	return q
+}
+
+// DoUpdateWhere restricts which existing rows are updated. Not supported by
+// MySQL. See SelectQuery.Where for cond.
+func (q *InsertQuery) DoUpdateWhere(cond interface{}, params ...interface{}) *InsertQuery {
+	q.conflict.where = append(q.conflict.where, toCondition(cond, params))
+	return q
+}
+
+// excludedValue sets a column to the value proposed for insertion.
+type excludedValue struct {
+	name string
+}
+
+func (e excludedValue) Name() string {
+	return e.name
+}
+
+// write writes the upsert clause. first is the first column of the insert.
+func (c *conflictClause) write(s *bytes.Buffer, params *[]interface{}, d Dialect, first string) error {
+	if c.action == conflictNone {
+		if len(c.target) > 0 || c.constraint != "" || len(c.where) > 0 {
+			return errors.New("conflict target requires DoNothing or DoUpdate")
+		}
+		return nil
+	}
+
+	if c.action == conflictUpdate && len(c.set) == 0 {
+		return errors.New("DoUpdate requires columns to update")
+	}
+
+	if d.isMySQL() {
+		return c.writeMySQL(s, params, d, first)
+	}
+
+	s.WriteString(" ON CONFLICT")
+
+	if c.constraint != "" {
+		if d.isSQLite() {
+			return fmt.Errorf("ON CONFLICT ON CONSTRAINT not supported by %s", d.Driver)
+		}
+		s.WriteString(" ON CONSTRAINT " + c.constraint)
+	} else if len(c.target) > 0 {
+		s.WriteString(" (" + strings.Join(c.target, ", ") + ")")
+	} else if c.action == conflictUpdate {
+		return errors.New("DoUpdate requires a conflict target")
+	}
+
+	if c.action == conflictNothing {
+		if len(c.where) > 0 {
+			return errors.New("DoUpdateWhere requires DoUpdate")
+		}
+		s.WriteString(" DO NOTHING")
+		return nil
+	}
+
+	s.WriteString(" DO UPDATE SET ")
+
+	for i := range c.set {
+		if i > 0 {
+			s.WriteString(", ")
+		}
+		switch v := c.set[i].(type) {
+		case excludedValue:
+			s.WriteString(v.name + "=excluded." + v.name)
+		case namedValue:
+			s.WriteString(v.name + "=" + v.value)
+		case namedParam:
+			s.WriteString(v.name + "=?")
+			*params = append(*params, v.param)
+		}
+	}
+
+	_, err := writeWhere(s, params, c.where, d, 0)
+
+	return err
+}
+
+func (c *conflictClause) writeMySQL(s *bytes.Buffer, params *[]interface{}, d Dialect, first string) error {
+	if c.constraint != "" {
+		return fmt.Errorf("ON CONFLICT ON CONSTRAINT not supported by %s", d.Driver)
+	}
+
+	if len(c.where) > 0 {
+		return fmt.Errorf("DoUpdateWhere not supported by %s", d.Driver)
+	}
+
+	s.WriteString(" ON DUPLICATE KEY UPDATE ")
+
+	if c.action == conflictNothing {
+		// A no-op update unlike INSERT IGNORE which also ignores other errors.
+		s.WriteString(first + "=" + first)
+		return nil
+	}
+
+	for i := range c.set {
+		if i > 0 {
+			s.WriteString(", ")
+		}
+		switch v := c.set[i].(type) {
+		case excludedValue:
+			s.WriteString(v.name + "=VALUES(" + v.name + ")")
+		case namedValue:
+			s.WriteString(v.name + "=" + v.value)
+		case namedParam:
+			s.WriteString(v.name + "=?")
+			*params = append(*params, v.param)
+		}
+	}
+
+	return nil
+}
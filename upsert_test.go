@@ -0,0 +1,117 @@
+package xl_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+const upsertSchema = `
+create table account (
+	id integer primary key,
+	email text not null unique,
+	name text not null,
+	logins integer not null default 0
+);
+
+insert into account (id, email, name, logins) values (1, 'alice@example.com', 'Alice', 1);
+`
+
+func TestUpsert(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, upsertSchema))
+
+	type account struct {
+		Email  string `db:"email"`
+		Name   string `db:"name"`
+		Logins int64  `db:"logins"`
+	}
+
+	{
+		q := xl.Insert("account")
+		q.Set("email", "alice@example.com")
+		q.Set("name", "Alice Örn")
+		q.OnConflict("email").DoNothing()
+		requireSQL(t, "INSERT INTO account (email, name) VALUES (?, ?) ON CONFLICT (email) DO NOTHING", q)
+
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(0), count)
+
+		st, err := q.Statement(xl.Dialect{Driver: "mysql"})
+		require.Nil(t, err)
+		require.Equal(t, "INSERT INTO account (email, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE email=email", st.SQL)
+	}
+
+	{
+		q := xl.Insert("account")
+		q.Set("email", "alice@example.com")
+		q.Set("name", "Alice Örn")
+		q.OnConflict("email").DoUpdate("name").DoUpdateSetRaw("logins", "account.logins+1")
+		requireSQL(t, "INSERT INTO account (email, name) VALUES (?, ?) ON CONFLICT (email) DO UPDATE SET name=excluded.name, logins=account.logins+1", q)
+		require.Nil(t, q.ExecErr(db))
+
+		var a account
+		require.Nil(t, xl.Select("email, name, logins").From("account").First(db, &a))
+		require.Equal(t, account{"alice@example.com", "Alice Örn", 2}, a)
+
+		st, err := q.Statement(xl.Dialect{Driver: "mysql"})
+		require.Nil(t, err)
+		require.Equal(t, "INSERT INTO account (email, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE name=VALUES(name), logins=account.logins+1", st.SQL)
+	}
+
+	{
+		q := xl.Insert("account")
+		q.Set("email", "alice@example.com")
+		q.Set("name", "Alice")
+		q.OnConflict("email").DoUpdate("name").DoUpdateWhere("account.logins>?", 5)
+		requireSQL(t, "INSERT INTO account (email, name) VALUES (?, ?) ON CONFLICT (email) DO UPDATE SET name=excluded.name WHERE account.logins>?", q)
+		require.Nil(t, q.ExecErr(db))
+
+		var name string
+		require.Nil(t, xl.Select("name").From("account").First(db, &name))
+		require.Equal(t, "Alice Örn", name)
+
+		_, err := q.Statement(xl.Dialect{Driver: "mysql"})
+		require.NotNil(t, err)
+	}
+
+	{
+		q := xl.Insert("account")
+		q.Set("email", "bob@example.com")
+		q.Set("name", "Bob")
+		q.OnConstraint("account_email_key").DoUpdateSet("logins", 0)
+
+		st, err := q.Statement(xl.Dialect{Driver: "postgres"})
+		require.Nil(t, err)
+		require.Equal(t, "INSERT INTO account (email, name) VALUES (?, ?) ON CONFLICT ON CONSTRAINT account_email_key DO UPDATE SET logins=?", st.SQL)
+		require.Equal(t, []interface{}{"bob@example.com", "Bob", 0}, st.Params)
+
+		_, err = q.Statement(db.Dialect())
+		require.NotNil(t, err)
+	}
+
+	{
+		q := xl.Insert("account")
+		q.Set("email", "carol@example.com")
+		q.Set("name", "Carol")
+		q.DoUpdate("name")
+		_, err := q.Statement(db.Dialect())
+		require.NotNil(t, err)
+	}
+
+	{
+		q := xl.Insert("account")
+		q.Set("email", "carol@example.com")
+		q.OnConflict("email").DoUpdate()
+		_, err := q.Statement(db.Dialect())
+		require.NotNil(t, err)
+		_, err = q.Statement(xl.Dialect{Driver: "mysql"})
+		require.NotNil(t, err)
+	}
+}
@@ -0,0 +1,256 @@
+package xl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A FrameBound is the start or end of a window frame.
+type FrameBound string
+
+const (
+	UnboundedPreceding FrameBound = "UNBOUNDED PRECEDING"
+	CurrentRow         FrameBound = "CURRENT ROW"
+	UnboundedFollowing FrameBound = "UNBOUNDED FOLLOWING"
+)
+
+// Preceding returns a frame bound n rows (or values for RANGE) before the
+// current row.
+func Preceding(n int64) FrameBound {
+	return FrameBound(fmt.Sprintf("%d PRECEDING", n))
+}
+
+// Following returns a frame bound n rows (or values for RANGE) after the
+// current row.
+func Following(n int64) FrameBound {
+	return FrameBound(fmt.Sprintf("%d FOLLOWING", n))
+}
+
+// A WindowSpec is a window definition, i.e. what goes inside OVER (...) or
+// WINDOW name AS (...).
+type WindowSpec struct {
+	base        string
+	partitionBy []string
+	orderBy     []Order
+	frame       string
+}
+
+// Window returns an empty window definition.
+//
+//	w := xl.Window().PartitionBy("department_id").OrderByDesc("salary")
+func Window() *WindowSpec {
+	return &WindowSpec{}
+}
+
+// WindowFrom returns a window definition that extends the named window.
+func WindowFrom(name string) *WindowSpec {
+	return &WindowSpec{base: name}
+}
+
+// PartitionBy adds expressions to the PARTITION BY clause.
+func (w *WindowSpec) PartitionBy(exprs ...string) *WindowSpec {
+	w.partitionBy = append(w.partitionBy, exprs...)
+	return w
+}
+
+// OrderBy appends expr to the ORDER BY clause of the window.
+func (w *WindowSpec) OrderBy(expr string, params ...interface{}) *WindowSpec {
+	w.orderBy = append(w.orderBy, Asc(expr, params...))
+	return w
+}
+
+// OrderByDesc appends expr in descending order to the ORDER BY clause of the
+// window.
+func (w *WindowSpec) OrderByDesc(expr string, params ...interface{}) *WindowSpec {
+	w.orderBy = append(w.orderBy, Desc(expr, params...))
+	return w
+}
+
+// AddOrder appends orders to the ORDER BY clause of the window.
+func (w *WindowSpec) AddOrder(orders ...Order) *WindowSpec {
+	w.orderBy = append(w.orderBy, orders...)
+	return w
+}
+
+// Rows sets the frame to ROWS BETWEEN start AND end.
+func (w *WindowSpec) Rows(start, end FrameBound) *WindowSpec {
+	w.frame = "ROWS BETWEEN " + string(start) + " AND " + string(end)
+	return w
+}
+
+// Range sets the frame to RANGE BETWEEN start AND end.
+func (w *WindowSpec) Range(start, end FrameBound) *WindowSpec {
+	w.frame = "RANGE BETWEEN " + string(start) + " AND " + string(end)
+	return w
+}
+
+func (w *WindowSpec) write(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	sep := ""
+
+	if w.base != "" {
+		s.WriteString(w.base)
+		sep = " "
+	}
+
+	if len(w.partitionBy) > 0 {
+		s.WriteString(sep + "PARTITION BY " + strings.Join(w.partitionBy, ", "))
+		sep = " "
+	}
+
+	for i := range w.orderBy {
+		if i == 0 {
+			s.WriteString(sep + "ORDER BY ")
+		} else {
+			s.WriteString(", ")
+		}
+		if err := w.orderBy[i].write(s, params, d); err != nil {
+			return err
+		}
+		sep = " "
+	}
+
+	if w.frame != "" {
+		s.WriteString(sep + w.frame)
+	}
+
+	return nil
+}
+
+// A WindowFunc is a window function call such as ROW_NUMBER() OVER (...).
+type WindowFunc struct {
+	call   string
+	params []interface{}
+	name   string
+	spec   *WindowSpec
+}
+
+// Func returns a window function from an SQL function call, typically an
+// aggregate.
+//
+//	xl.Func("SUM(salary)").Over(xl.Window().PartitionBy("department_id"))
+func Func(call string, params ...interface{}) *WindowFunc {
+	return &WindowFunc{call: call, params: params}
+}
+
+// RowNumber returns ROW_NUMBER().
+func RowNumber() *WindowFunc {
+	return Func("ROW_NUMBER()")
+}
+
+// Rank returns RANK().
+func Rank() *WindowFunc {
+	return Func("RANK()")
+}
+
+// DenseRank returns DENSE_RANK().
+func DenseRank() *WindowFunc {
+	return Func("DENSE_RANK()")
+}
+
+// Lag returns LAG(expr, offset).
+func Lag(expr string, offset int64) *WindowFunc {
+	return Func(fmt.Sprintf("LAG(%s, %d)", expr, offset))
+}
+
+// Lead returns LEAD(expr, offset).
+func Lead(expr string, offset int64) *WindowFunc {
+	return Func(fmt.Sprintf("LEAD(%s, %d)", expr, offset))
+}
+
+// Over sets the window of the function.
+func (f *WindowFunc) Over(w *WindowSpec) *WindowFunc {
+	f.spec = w
+	f.name = ""
+	return f
+}
+
+// OverName makes the function use a window defined with SelectQuery.Window.
+func (f *WindowFunc) OverName(name string) *WindowFunc {
+	f.name = name
+	f.spec = nil
+	return f
+}
+
+func (f *WindowFunc) write(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	if err := writeExpr(s, params, f.call, f.params, d); err != nil {
+		return err
+	}
+
+	if f.name != "" {
+		s.WriteString(" OVER " + f.name)
+		return nil
+	}
+
+	s.WriteString(" OVER (")
+	if f.spec != nil {
+		if err := f.spec.write(s, params, d); err != nil {
+			return err
+		}
+	}
+	s.WriteString(")")
+
+	return nil
+}
+
+type windowColumn struct {
+	fn    *WindowFunc
+	alias string
+}
+
+func (c windowColumn) writeColumn(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	if err := c.fn.write(s, params, d); err != nil {
+		return err
+	}
+	if c.alias != "" {
+		s.WriteString(" \"" + c.alias + "\"")
+	}
+	return nil
+}
+
+type namedWindow struct {
+	name string
+	spec *WindowSpec
+}
+
+// WindowColumn adds a window function column. The column is named alias in
+// the result.
+//
+//	q.WindowColumn(xl.Rank().Over(xl.Window().OrderByDesc("salary")), "rank")
+func (q *SelectQuery) WindowColumn(fn *WindowFunc, alias string) {
+	q.exprs = append(q.exprs, windowColumn{fn, alias})
+}
+
+// Window adds a named window to the WINDOW clause. Use WindowFunc.OverName
+// to refer to it.
+func (q *SelectQuery) Window(name string, w *WindowSpec) {
+	q.windows = append(q.windows, namedWindow{name, w})
+}
+
+func writeWindows(s *bytes.Buffer, params *[]interface{}, windows []namedWindow, d Dialect) error {
+	for i, w := range windows {
+		if i == 0 {
+			s.WriteString(" WINDOW ")
+		} else {
+			s.WriteString(", ")
+		}
+		s.WriteString(w.name + " AS (")
+		if err := w.spec.write(s, params, d); err != nil {
+			return err
+		}
+		s.WriteString(")")
+	}
+
+	return nil
+}
+
+func copyWindows(a []namedWindow) []namedWindow {
+	if a == nil {
+		return nil
+	}
+
+	b := make([]namedWindow, len(a))
+	copy(b, a)
+
+	return b
+}
@@ -0,0 +1,79 @@
+package xl_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func TestWindow(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	postgres := xl.Dialect{BindType: sqlx.DOLLAR, Driver: "postgres"}
+
+	{
+		q := xl.Select("name").From("employee")
+		q.Where("salary>?", 1000)
+		q.WindowColumn(xl.Rank().Over(xl.Window().PartitionBy("department_id").OrderByDesc("salary")), "rank")
+		q.WindowColumn(xl.Func("SUM(salary)").Over(xl.Window().OrderBy("id").Rows(xl.UnboundedPreceding, xl.CurrentRow)), "running")
+		q.WindowColumn(xl.Lag("salary", 1).Over(xl.Window().OrderBy("id")), "prev")
+		q.OrderBy("id")
+		requireSQL(t, `SELECT name, RANK() OVER (PARTITION BY department_id ORDER BY salary DESC) "rank", SUM(salary) OVER (ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) "running", LAG(salary, 1) OVER (ORDER BY id) "prev" FROM employee WHERE salary>? ORDER BY id`, q)
+
+		var e []struct {
+			Name    string        `db:"name"`
+			Rank    int64         `db:"rank"`
+			Running int64         `db:"running"`
+			Prev    sql.NullInt64 `db:"prev"`
+		}
+		require.Nil(t, q.All(db, &e))
+		require.Equal(t, 5, len(e))
+		require.Equal(t, "Alice Örn", e[0].Name)
+		require.Equal(t, []int64{1, 2, 2, 3, 1}, []int64{e[0].Rank, e[1].Rank, e[2].Rank, e[3].Rank, e[4].Rank})
+		require.Equal(t, []int64{12000, 21000, 31000, 39000, 50000}, []int64{e[0].Running, e[1].Running, e[2].Running, e[3].Running, e[4].Running})
+		require.False(t, e[0].Prev.Valid)
+		require.Equal(t, int64(8000), e[4].Prev.Int64)
+	}
+
+	{
+		var e []struct {
+			Name string `db:"name"`
+			N    int64  `db:"n"`
+		}
+		q := xl.Select("name").From("employee")
+		q.WindowColumn(xl.RowNumber().Over(xl.WindowFrom("w").OrderBy("salary")), "n")
+		q.Window("w", xl.Window().PartitionBy("department_id"))
+		q.OrderBy("n, department_id")
+		requireSQL(t, `SELECT name, ROW_NUMBER() OVER (w ORDER BY salary) "n" FROM employee WINDOW w AS (PARTITION BY department_id) ORDER BY n, department_id`, q)
+		require.Nil(t, q.All(db, &e))
+		require.Equal(t, 5, len(e))
+		names := make([]string, len(e))
+		for i := range e {
+			names[i] = e[i].Name
+		}
+		require.Equal(t, []string{"Cecil Ål", "David Zygot", "Alice Örn", "Bob Älv", "Eliza Yxa"}, names)
+	}
+
+	{
+		q := xl.Select("name").From("employee")
+		q.WindowColumn(xl.Func("COALESCE(SUM(salary), ?)", 0).OverName("w"), "total")
+		q.WindowColumn(xl.RowNumber().Over(xl.WindowFrom("w").OrderBy("salary*?", 2)), "n")
+		q.WindowColumn(xl.DenseRank().Over(xl.Window()), "")
+		q.Window("w", xl.Window().PartitionBy("department_id", "city").Range(xl.Preceding(2), xl.Following(3)))
+		q.Where("id>?", 3)
+		q.OrderBy("id+?", 4)
+
+		st, err := q.Statement(postgres)
+		require.Nil(t, err)
+		require.Equal(t, `SELECT name, COALESCE(SUM(salary), $1) OVER w "total", ROW_NUMBER() OVER (w ORDER BY salary*$2) "n", DENSE_RANK() OVER () FROM employee WHERE id>$3 WINDOW w AS (PARTITION BY department_id, city RANGE BETWEEN 2 PRECEDING AND 3 FOLLOWING) ORDER BY id+$4`, st.SQL)
+		require.Equal(t, []interface{}{0, 2, 3, 4}, st.Params)
+	}
+}
@@ -0,0 +1,72 @@
+package xl
+
+import (
+	"bytes"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// withClause holds the common table expressions of a query.
+type withClause struct {
+	recursive bool
+	ctes      []commonTable
+}
+
+type commonTable struct {
+	name  string
+	query Statementer
+}
+
+func (w *withClause) add(name string, query Statementer, recursive bool) {
+	if recursive {
+		w.recursive = true
+	}
+	w.ctes = append(w.ctes, commonTable{name, query})
+}
+
+func (w withClause) copy() withClause {
+	ctes := make([]commonTable, len(w.ctes))
+	copy(ctes, w.ctes)
+	return withClause{w.recursive, ctes}
+}
+
+// write writes the WITH clause followed by a space. The common table
+// expressions are compiled with ? placeholders so that the whole statement
+// can be rebound at once.
+func (w withClause) write(s *bytes.Buffer, params *[]interface{}, d Dialect) error {
+	if len(w.ctes) == 0 {
+		return nil
+	}
+
+	s.WriteString("WITH ")
+
+	if w.recursive {
+		s.WriteString("RECURSIVE ")
+	}
+
+	for i, cte := range w.ctes {
+		st, err := cte.query.Statement(d.unbound())
+
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			s.WriteString(", ")
+		}
+
+		s.WriteString(cte.name + " AS (" + st.SQL + ")")
+		*params = append(*params, st.Params...)
+	}
+
+	s.WriteString(" ")
+
+	return nil
+}
+
+// unbound returns a copy of d that keeps ? placeholders. Used when compiling
+// statements that will be embedded in another statement.
+func (d Dialect) unbound() Dialect {
+	d.BindType = sqlx.QUESTION
+	return d
+}
@@ -0,0 +1,97 @@
+package xl_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/tomyl/xl"
+	"github.com/tomyl/xl/testlogger"
+)
+
+func TestWith(t *testing.T) {
+	xl.SetLogger(testlogger.Simple(t))
+
+	db, err := xl.Open("sqlite3", ":memory:")
+	require.Nil(t, err)
+	require.Nil(t, xl.MultiExec(db, selectSchema))
+
+	{
+		var names []string
+
+		rich := xl.Select("id, name").From("employee")
+		rich.Where("salary>?", 10000)
+
+		q := xl.Select("name").From("rich")
+		q.With("rich", rich)
+		q.Where("id<>?", 1)
+		q.OrderBy("name")
+
+		requireSQL(t, "WITH rich AS (SELECT id, name FROM employee WHERE salary>?) SELECT name FROM rich WHERE id<>? ORDER BY name", q)
+
+		st, err := q.Statement(xl.Dialect{BindType: sqlx.DOLLAR})
+		require.Nil(t, err)
+		require.Equal(t, "WITH rich AS (SELECT id, name FROM employee WHERE salary>$1) SELECT name FROM rich WHERE id<>$2 ORDER BY name", st.SQL)
+		require.Equal(t, []interface{}{10000, 1}, st.Params)
+
+		require.Nil(t, q.All(db, &names))
+		require.Equal(t, []string{"Eliza Yxa"}, names)
+
+		total, err := q.Total(db)
+		require.Nil(t, err)
+		require.Equal(t, 1, total)
+	}
+
+	{
+		var ns []int
+
+		step := xl.Select("n+?", 1).From("t")
+		step.Where("n<?", 5)
+
+		q := xl.Select("n").From("t")
+		q.WithRecursive("t(n)", xl.UnionAll(xl.Select("?", 1), step))
+
+		requireSQL(t, "WITH RECURSIVE t(n) AS (SELECT ? UNION ALL SELECT n+? FROM t WHERE n<?) SELECT n FROM t", q)
+		require.Nil(t, q.All(db, &ns))
+		require.Equal(t, []int{1, 2, 3, 4, 5}, ns)
+	}
+
+	{
+		hr := xl.Select("id").From("department")
+		hr.Where("name=?", "HR")
+
+		q := xl.Update("employee")
+		q.With("hr", hr)
+		q.Set("salary", 1)
+		q.Where("department_id IN (SELECT id FROM hr)")
+		requireSQL(t, "WITH hr AS (SELECT id FROM department WHERE name=?) UPDATE employee SET salary=? WHERE department_id IN (SELECT id FROM hr)", q)
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(2), count)
+	}
+
+	{
+		poor := xl.Select("id").From("employee")
+		poor.Where("salary<?", 10000)
+
+		q := xl.Delete("employee")
+		q.With("poor", poor)
+		q.Where("id IN (SELECT id FROM poor)")
+		requireSQL(t, "WITH poor AS (SELECT id FROM employee WHERE salary<?) DELETE FROM employee WHERE id IN (SELECT id FROM poor)", q)
+		count, err := q.ExecCount(db)
+		require.Nil(t, err)
+		require.Equal(t, int64(4), count)
+	}
+
+	{
+		q := xl.Insert("department")
+		q.With("c", xl.Select("?", "Paris"))
+		q.Set("name", "Sales")
+		q.SetRaw("city", "(SELECT * FROM c)")
+		requireSQL(t, "WITH c AS (SELECT ?) INSERT INTO department (name, city) VALUES (?, (SELECT * FROM c))", q)
+		st, err := q.Statement(xl.Dialect{})
+		require.Nil(t, err)
+		require.Equal(t, []interface{}{"Paris", "Sales"}, st.Params)
+		require.Nil(t, q.ExecErr(db))
+	}
+}
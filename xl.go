@@ -2,6 +2,7 @@
 package xl
 
 import (
+	"context"
 	"database/sql"
 	"strings"
 	"time"
@@ -23,34 +24,81 @@ func SetLogger(fn Logger) {
 type Dialect struct {
 	// sqlx bind type
 	BindType int
+
+	// Driver name, e.g. "postgres" or "sqlite3". Used for SQL that differs
+	// between databases. Standard SQL is generated if empty.
+	Driver string
+
+	// Maximum number of bind parameters per statement. Batch inserts are
+	// split to stay within the limit. Defaults to the limit of Driver if
+	// zero. Negative means no limit.
+	MaxParams int
+}
+
+func (d Dialect) maxParams() int {
+	if d.MaxParams != 0 {
+		return d.MaxParams
+	}
+	switch {
+	case d.isSQLite():
+		return 999
+	case d.isPostgres(), d.isMySQL():
+		return 65535
+	}
+	return 0
+}
+
+func (d Dialect) isPostgres() bool {
+	switch d.Driver {
+	case "postgres", "pgx", "pq-timeouts", "cloudsqlpostgres":
+		return true
+	}
+	return false
+}
+
+func (d Dialect) isMySQL() bool {
+	return d.Driver == "mysql"
+}
+
+func (d Dialect) isSQLite() bool {
+	switch d.Driver {
+	case "sqlite3", "sqlite":
+		return true
+	}
+	return false
 }
 
 // A DB is a wrapper type around sqlx.DB that implements xl.Execer and xl.Queryer interfaces.
 type DB struct {
 	*sqlx.DB
+	maxAffected int64
+	retry       *RetryPolicy
 }
 
 // NewDB wraps an sqlx.DB object.
 func NewDB(db *sqlx.DB) *DB {
-	return &DB{db}
+	return &DB{DB: db}
 }
 
 // Dialect returns a Dialect based on this database connection.
 func (db *DB) Dialect() Dialect {
 	return Dialect{
 		BindType: sqlx.BindType(db.DriverName()),
+		Driver:   db.DriverName(),
 	}
 }
 
 // Beginxl starts a transaction.
 func (db *DB) Beginxl() (*Tx, error) {
-	tx, err := db.Beginx()
-
-	if err != nil {
-		return nil, err
-	}
+	return db.BeginTxl(context.Background(), nil)
+}
 
-	return &Tx{db, tx, false, false}, nil
+// BeginTxl starts a transaction with options. The transaction is rolled back
+// if ctx is canceled or the timeout of opts expires.
+//
+//	tx, err := db.BeginTxl(ctx, &xl.TxOptions{Isolation: sql.LevelSerializable})
+func (db *DB) BeginTxl(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	return (&Tx{db: db}).begin(ctx, opts)
 }
 
 // Open connects to a database.
@@ -76,6 +124,7 @@ func Connect(driver, dsn string) (*DB, error) {
 type Execer interface {
 	Dialect() Dialect
 	Exec(query string, args ...interface{}) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
 // Execer can execute an SQL query and fetch fetch the ros and is also aware of
@@ -85,6 +134,9 @@ type Queryer interface {
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 	Queryx(query string, args ...interface{}) (*sqlx.Rows, error)
 	QueryRowx(query string, args ...interface{}) *sqlx.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
 }
 
 type tableAlias struct {
@@ -141,6 +193,12 @@ func NextInt64(db Queryer, seq string) (int64, error) {
 	return pos, err
 }
 
+func NextInt64Context(ctx context.Context, db Queryer, seq string) (int64, error) {
+	var pos int64
+	err := New("SELECT NEXTVAL('"+seq+"')").FirstContext(ctx, db, &pos)
+	return pos, err
+}
+
 // MultiExec executes a batch of SQL statements. Based on MultiExec from
 // sqlx_test.go at github.com/jmoiron/sqlx.
 func MultiExec(e sqlx.Execer, query string) error {
@@ -157,6 +215,9 @@ func MultiExec(e sqlx.Execer, query string) error {
 	return nil
 }
 
+// Placeholders returns a parenthesized list of n placeholders. Builders expand
+// slice parameters automatically so this is mostly useful for hand-written SQL
+// passed to New.
 func Placeholders(n int) string {
 	if n <= 0 {
 		return "()"